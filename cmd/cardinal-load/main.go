@@ -0,0 +1,97 @@
+// Command cardinal-load runs scripted USSD flows against an HTTP endpoint with
+// many concurrent virtual users and prints throughput, latency percentiles,
+// error and timeout rates.
+//
+//	cardinal-load -url http://localhost:8080/ussd/at -flows 'testdata/scenarios/*.json' \
+//	    -users 200 -rate 50 -duration 2m -think 2s -jitter 3s -accumulate
+//
+// Flow files use the testkit scenario format. The endpoint must accept
+// Africa's Talking style form posts (transport.AfricaTalkingHandler or
+// transport.HTTPHandler); rename fields with -fields text=USSD_STRING,...
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/grahms/cardinal/load"
+)
+
+func main() {
+	var (
+		url        = flag.String("url", "", "USSD endpoint (required)")
+		flows      = flag.String("flows", "", "glob of flow/scenario JSON files (required)")
+		users      = flag.Int("users", 10, "concurrent virtual users")
+		rate       = flag.Float64("rate", 0, "new sessions per second (0 = as fast as users free up)")
+		duration   = flag.Duration("duration", 10*time.Second, "how long to start sessions")
+		sessions   = flag.Int("sessions", 0, "stop after this many sessions (0 = no limit)")
+		think      = flag.Duration("think", 0, "think time before each input")
+		jitter     = flag.Duration("jitter", 0, "random extra think time, up to this much")
+		timeout    = flag.Duration("timeout", 5*time.Second, "step timeout")
+		accumulate = flag.Bool("accumulate", false, `send accumulated input ("1*100*1")`)
+		prefix     = flag.String("prefix", "+25884", "MSISDN prefix for virtual users")
+		fields     = flag.String("fields", "", "form field renames, e.g. sessionId=SESSION_ID,text=USSD_STRING")
+		seed       = flag.Int64("seed", 1, "random seed")
+	)
+	flag.Parse()
+	if *url == "" || *flows == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	files, err := filepath.Glob(*flows)
+	if err != nil || len(files) == 0 {
+		fail("no flow files match %q", *flows)
+	}
+	var all []load.Flow
+	for _, f := range files {
+		fl, err := load.LoadFlows(f)
+		if err != nil {
+			fail("%v", err)
+		}
+		all = append(all, fl...)
+	}
+
+	target := &load.HTTPTarget{URL: *url, Fields: map[string]string{}}
+	for _, kv := range strings.Split(*fields, ",") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(kv), "="); ok {
+			target.Fields[k] = v
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Fprintf(os.Stderr, "running %d flows with %d users for %s against %s\n", len(all), *users, *duration, *url)
+	rep, err := load.Run(ctx, load.Config{
+		Target:       target,
+		Flows:        all,
+		Users:        *users,
+		Rate:         *rate,
+		Duration:     *duration,
+		Sessions:     *sessions,
+		ThinkTime:    *think,
+		ThinkJitter:  *jitter,
+		StepTimeout:  *timeout,
+		Accumulate:   *accumulate,
+		MsisdnPrefix: *prefix,
+		Seed:         *seed,
+	})
+	if err != nil {
+		fail("%v", err)
+	}
+	fmt.Print(rep)
+	if rep.Completed < rep.Sessions {
+		os.Exit(1)
+	}
+}
+
+func fail(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "cardinal-load: "+format+"\n", args...)
+	os.Exit(2)
+}
@@ -0,0 +1,38 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// Clock abstracts the current time so session expiry, rate-limit windows and
+// time-of-day rules can be tested deterministically.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the wall clock.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time { return time.Now() }
+
+type clockKey struct{}
+
+// WithClock returns a context carrying c. The Engine does this for
+// Config.Clock; stores and middleware read it back with Now.
+func WithClock(ctx context.Context, c Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, c)
+}
+
+// ClockFrom returns the clock carried by ctx, or SystemClock.
+func ClockFrom(ctx context.Context) Clock {
+	if ctx != nil {
+		if c, ok := ctx.Value(clockKey{}).(Clock); ok {
+			return c
+		}
+	}
+	return SystemClock{}
+}
+
+// Now is ClockFrom(ctx).Now().
+func Now(ctx context.Context) time.Time { return ClockFrom(ctx).Now() }
@@ -6,12 +6,48 @@ import (
 	"time"
 )
 
+// Kind tells the engine what a gateway callback means.
+type Kind uint8
+
+const (
+	KindUnspecified Kind = iota // adapter has no flag; the engine infers from session state
+	KindBegin                   // user dialled the code: start a fresh session
+	KindContinue                // user answered the current screen
+	KindAbort                   // user cancelled / gateway released the session
+	KindTimeout                 // gateway gave up waiting for the user
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindBegin:
+		return "begin"
+	case KindContinue:
+		return "continue"
+	case KindAbort:
+		return "abort"
+	case KindTimeout:
+		return "timeout"
+	}
+	return "unspecified"
+}
+
+// ParseKind is the inverse of Kind.String. Unknown names report false.
+func ParseKind(s string) (Kind, bool) {
+	for _, k := range []Kind{KindUnspecified, KindBegin, KindContinue, KindAbort, KindTimeout} {
+		if k.String() == s {
+			return k, true
+		}
+	}
+	return KindUnspecified, false
+}
+
 // Request is the normalized inbound USSD request from an aggregator/MNO.
 type Request struct {
 	SessionID   string
 	Msisdn      string
 	ServiceCode string
 	Text        string            // raw text e.g. "1*200"
+	Kind        Kind              // begin/continue/abort/timeout, when the gateway says so
 	Meta        map[string]string // optional vendor-specific metadata
 }
 
@@ -48,6 +84,15 @@ func (s *Session) MustInt(k string) int {
 	return 0
 }
 
+// Clone returns a session with the same id and a shallow copy of its data.
+func (s *Session) Clone() *Session {
+	cp := make(map[string]any, len(s.data))
+	for k, v := range s.data {
+		cp[k] = v
+	}
+	return &Session{id: s.id, data: cp}
+}
+
 // Store is a pluggable session store (e.g., in-memory, Redis).
 type Store interface {
 	Get(ctx context.Context, sid string) (map[string]any, error)
@@ -55,15 +100,48 @@ type Store interface {
 	Del(ctx context.Context, sid string) error
 }
 
+// Counter is optionally implemented by a Store with native atomic counters
+// (e.g. Redis INCR + PEXPIRE). Counters shared across replicas, such as
+// middleware.StoreRateBackend, use it when present; without it they fall back
+// to a read-modify-write that can lose concurrent increments.
+type Counter interface {
+	// Incr atomically adds one to key and returns the new value. ttl applies
+	// when the key is created.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// Count returns the current value of key (0 if missing or expired).
+	Count(ctx context.Context, key string) (int64, error)
+}
+
 // App is implemented by your application. Cardinal calls Handle for each step.
 type App interface {
 	Handle(ctx context.Context, s *Session, req Request) (Reply, error)
 }
 
+// Aborter is optionally implemented by an App to clean up after sessions the
+// gateway aborted or timed out. No reply is shown to the user.
+type Aborter interface {
+	Abort(ctx context.Context, s *Session, req Request)
+}
+
 // Config for the Engine.
 type Config struct {
 	Store      Store
 	SessionTTL time.Duration // default 60s if zero
+
+	// RequestTimeout bounds each step: the App sees a context with this deadline.
+	// Zero means no deadline beyond the caller's context.
+	RequestTimeout time.Duration
+
+	// Clock is passed to the store, middleware and handlers through the context
+	// (see WithClock) unless the caller's context already carries one.
+	// Default: the wall clock.
+	Clock Clock
+}
+
+// Handler processes one USSD step. Transports accept any Handler: an *Engine,
+// or something that picks an Engine per request (e.g. dispatch.Dispatcher).
+type Handler interface {
+	Handle(ctx context.Context, req Request) (Reply, error)
 }
 
 // Engine coordinates session state and calls the App.
@@ -72,6 +150,8 @@ type Engine struct {
 	app App
 }
 
+var _ Handler = (*Engine)(nil)
+
 func New(app App, cfg Config) *Engine {
 	if cfg.SessionTTL == 0 {
 		cfg.SessionTTL = 60 * time.Second
@@ -79,20 +159,62 @@ func New(app App, cfg Config) *Engine {
 	return &Engine{cfg: cfg, app: app}
 }
 
+// Store returns the session store the engine reads and writes.
+func (e *Engine) Store() Store { return e.cfg.Store }
+
 // Handle processes a single USSD step. It loads the session, delegates to the app,
 // and persists or deletes the session depending on the reply.
+//
+// Abort and timeout requests never reach the app's normal handlers: the app's
+// Abort hook (if any) runs and the session is deleted. Begin requests start
+// from empty session data even if a stale session exists.
 func (e *Engine) Handle(ctx context.Context, req Request) (Reply, error) {
+	return e.handle(ctx, req, nil)
+}
+
+// Start begins a fresh session whose data is seeded before the app runs, e.g.
+// for network-initiated dialogues that must open on a specific screen.
+// req.Kind is forced to KindBegin.
+func (e *Engine) Start(ctx context.Context, req Request, seed map[string]any) (Reply, error) {
+	req.Kind = KindBegin
+	return e.handle(ctx, req, seed)
+}
+
+func (e *Engine) handle(ctx context.Context, req Request, seed map[string]any) (Reply, error) {
 	if req.SessionID == "" {
 		return END("Invalid session"), errors.New("missing session id")
 	}
+	if e.cfg.Clock != nil && ctx.Value(clockKey{}) == nil {
+		ctx = WithClock(ctx, e.cfg.Clock)
+	}
 
-	data, _ := e.cfg.Store.Get(ctx, req.SessionID)
+	var data map[string]any
+	if req.Kind != KindBegin {
+		data, _ = e.cfg.Store.Get(ctx, req.SessionID)
+	}
 	if data == nil {
-		data = map[string]any{}
+		data = make(map[string]any, len(seed))
+	}
+	for k, v := range seed {
+		data[k] = v
 	}
 	s := &Session{id: req.SessionID, data: data}
 
-	reply, err := e.app.Handle(ctx, s, req)
+	if req.Kind == KindAbort || req.Kind == KindTimeout {
+		if ab, ok := e.app.(Aborter); ok {
+			ab.Abort(ctx, s, req)
+		}
+		_ = e.cfg.Store.Del(ctx, req.SessionID)
+		return END(""), nil
+	}
+
+	actx := ctx
+	if e.cfg.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		actx, cancel = context.WithTimeout(ctx, e.cfg.RequestTimeout)
+		defer cancel()
+	}
+	reply, err := e.app.Handle(actx, s, req)
 
 	if err != nil || !reply.Continue {
 		_ = e.cfg.Store.Del(ctx, req.SessionID)
@@ -0,0 +1,15 @@
+package core
+
+import "testing"
+
+func TestParseKind(t *testing.T) {
+	for _, k := range []Kind{KindUnspecified, KindBegin, KindContinue, KindAbort, KindTimeout} {
+		got, ok := ParseKind(k.String())
+		if !ok || got != k {
+			t.Errorf("ParseKind(%q) = %v, %v; want %v, true", k.String(), got, ok, k)
+		}
+	}
+	if k, ok := ParseKind("release"); ok || k != KindUnspecified {
+		t.Errorf("ParseKind(unknown) = %v, %v", k, ok)
+	}
+}
@@ -0,0 +1,174 @@
+// Package dispatch serves several USSD service codes from one process.
+//
+// A Dispatcher maps codes such as "*144#" and "*144*5#" to their own engine:
+// a different app (or the same router entered at a different start path),
+// its own session TTL and timeout, and a session namespace of its own in the
+// store. It implements core.Handler, so any transport can serve it.
+//
+//	d := dispatch.New(st,
+//	    dispatch.Service{Code: "*144#", App: airtime.Mount()},
+//	    dispatch.Service{Code: "*144*5#", App: airtime.MountAt("/bundles")},
+//	    dispatch.Service{Code: "*555#", App: wallet.Mount(), SessionTTL: 2 * time.Minute},
+//	)
+//	mux.Handle("/ussd", transport.HTTPHandler(d))
+package dispatch
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+)
+
+// Service is the per-code configuration.
+type Service struct {
+	Code           string        // "*144#", "*144*5#", or "144" (normalized either way)
+	App            core.App      // e.g. r.Mount() or r.MountAt("/bundles")
+	Store          core.Store    // default: the dispatcher's store
+	SessionTTL     time.Duration // default 60s
+	RequestTimeout time.Duration // see core.Config
+}
+
+// Dispatcher routes each request to the engine of the longest matching service code.
+type Dispatcher struct {
+	st       core.Store
+	mu       sync.RWMutex
+	services map[string]*core.Engine // normalized code -> engine
+
+	// Unknown is shown for codes with no service (default END "Unknown service.").
+	Unknown core.Reply
+	// BindTTL is how long a session remembers its service when later steps
+	// arrive without a service code (default 5m).
+	BindTTL time.Duration
+}
+
+func New(st core.Store, services ...Service) *Dispatcher {
+	d := &Dispatcher{
+		st:       st,
+		services: map[string]*core.Engine{},
+		Unknown:  core.END("Unknown service."),
+		BindTTL:  5 * time.Minute,
+	}
+	for _, s := range services {
+		d.Add(s)
+	}
+	return d
+}
+
+// Add registers (or replaces) a service. It is safe to call while serving.
+func (d *Dispatcher) Add(s Service) {
+	code := Normalize(s.Code)
+	st := s.Store
+	if st == nil {
+		st = d.st
+	}
+	eng := core.New(s.App, core.Config{
+		Store:          &namespaced{prefix: "svc:" + code + ":", st: st},
+		SessionTTL:     s.SessionTTL,
+		RequestTimeout: s.RequestTimeout,
+	})
+	d.mu.Lock()
+	d.services[code] = eng
+	d.mu.Unlock()
+}
+
+// Handle implements core.Handler.
+//
+// The matched code is kept in Meta["service"] and any extra dialled segments
+// (e.g. "100" in "*144*5*100#" when only "*144*5#" is registered) in
+// Meta["service_extra"].
+func (d *Dispatcher) Handle(ctx context.Context, req core.Request) (core.Reply, error) {
+	return d.handle(ctx, req, nil, false)
+}
+
+// Start begins a fresh, seeded session on the service matching
+// req.ServiceCode (see core.Engine.Start), e.g. for push.Pusher.
+func (d *Dispatcher) Start(ctx context.Context, req core.Request, seed map[string]any) (core.Reply, error) {
+	return d.handle(ctx, req, seed, true)
+}
+
+func (d *Dispatcher) handle(ctx context.Context, req core.Request, seed map[string]any, start bool) (core.Reply, error) {
+	code := Normalize(req.ServiceCode)
+	if code == "" && req.SessionID != "" && !start {
+		code = d.bound(ctx, req.SessionID)
+	}
+	eng, match, extra := d.match(code)
+	if eng == nil {
+		return d.Unknown, nil
+	}
+	if req.Meta == nil {
+		req.Meta = map[string]string{}
+	}
+	req.Meta["service"] = match
+	if extra != "" {
+		req.Meta["service_extra"] = extra
+	}
+	var rep core.Reply
+	var err error
+	if start {
+		rep, err = eng.Start(ctx, req, seed)
+	} else {
+		rep, err = eng.Handle(ctx, req)
+	}
+	if req.SessionID != "" {
+		if rep.Continue && err == nil {
+			_ = d.st.Put(ctx, bindKey(req.SessionID), map[string]any{"code": match}, d.BindTTL)
+		} else {
+			_ = d.st.Del(ctx, bindKey(req.SessionID))
+		}
+	}
+	return rep, err
+}
+
+// match finds the longest registered code that prefixes code by "*" segments.
+func (d *Dispatcher) match(code string) (*core.Engine, string, string) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	segs := strings.Split(code, "*")
+	for n := len(segs); n > 0; n-- {
+		c := strings.Join(segs[:n], "*")
+		if eng, ok := d.services[c]; ok {
+			return eng, c, strings.Join(segs[n:], "*")
+		}
+	}
+	return nil, "", ""
+}
+
+func (d *Dispatcher) bound(ctx context.Context, sid string) string {
+	data, _ := d.st.Get(ctx, bindKey(sid))
+	code, _ := data["code"].(string)
+	return code
+}
+
+func bindKey(sid string) string { return "svcbind:" + sid }
+
+// Normalize reduces a service code to its "*"-separated digits:
+// "*144*5#" and "144*5" both become "144*5".
+func Normalize(code string) string {
+	code = strings.TrimSpace(code)
+	code = strings.TrimPrefix(code, "*")
+	code = strings.TrimSuffix(code, "#")
+	return code
+}
+
+// namespaced prefixes session ids so services never see each other's sessions.
+type namespaced struct {
+	prefix string
+	st     core.Store
+}
+
+func (n *namespaced) Get(ctx context.Context, sid string) (map[string]any, error) {
+	return n.st.Get(ctx, n.prefix+sid)
+}
+
+func (n *namespaced) Put(ctx context.Context, sid string, data map[string]any, ttl time.Duration) error {
+	return n.st.Put(ctx, n.prefix+sid, data, ttl)
+}
+
+func (n *namespaced) Del(ctx context.Context, sid string) error {
+	return n.st.Del(ctx, n.prefix+sid)
+}
+
+var _ core.Handler = (*Dispatcher)(nil)
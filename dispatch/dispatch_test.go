@@ -0,0 +1,131 @@
+package dispatch
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/router"
+	"github.com/grahms/cardinal/store"
+)
+
+// counter shows its name, the dispatch meta and how many screens this
+// session has seen; input "0" ends the session.
+func counter(name string) core.App {
+	r := router.New("/home")
+	r.SHOW("/home", func(c *router.Ctx) core.Reply {
+		n, _ := c.Get("n")
+		k, _ := n.(int)
+		c.Set("n", k+1)
+		return core.CON(fmt.Sprintf("%s %s/%s n=%d", name, c.Req.Meta["service"], c.Req.Meta["service_extra"], k+1))
+	})
+	r.INPUT("/home", func(c *router.Ctx) core.Reply {
+		if c.In() == "0" {
+			return core.END(name + " bye")
+		}
+		return core.CON("")
+	})
+	return r.Mount()
+}
+
+func newDispatcher() *Dispatcher {
+	return New(store.NewInMemoryStore(time.Minute, store.WithoutGC()),
+		Service{Code: "*144#", App: counter("airtime")},
+		Service{Code: "*144*5#", App: counter("bundles")},
+		Service{Code: "555", App: counter("wallet")},
+	)
+}
+
+func TestLongestPrefix(t *testing.T) {
+	d := newDispatcher()
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"*144#", "airtime 144/ n=1"},
+		{"*144*5#", "bundles 144*5/ n=1"},
+		{"*144*5*100#", "bundles 144*5/100 n=1"},
+		{"*144*7#", "airtime 144/7 n=1"},
+		{"*555#", "wallet 555/ n=1"},
+		{"*999#", "Unknown service."},
+		{"*14#", "Unknown service."},
+	}
+	for i, tt := range tests {
+		rep, err := d.Handle(context.Background(), core.Request{SessionID: fmt.Sprint("s", i), ServiceCode: tt.code})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rep.Message != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.code, rep.Message, tt.want)
+		}
+	}
+}
+
+func TestStepWithoutServiceCode(t *testing.T) {
+	d := newDispatcher()
+	ctx := context.Background()
+	step := func(code, text string) string {
+		t.Helper()
+		rep, err := d.Handle(ctx, core.Request{SessionID: "s1", ServiceCode: code, Text: text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rep.Message
+	}
+	if got := step("*144*5#", ""); got != "bundles 144*5/ n=1" {
+		t.Fatalf("start: %q", got)
+	}
+	if got := step("", "1"); got != "bundles 144*5/ n=2" {
+		t.Fatalf("step without code: %q, want the bound service", got)
+	}
+	if got := step("", "0"); got != "bundles bye" {
+		t.Fatalf("end: %q", got)
+	}
+	if got := step("", "1"); got != "Unknown service." {
+		t.Fatalf("after END: %q, want the binding gone", got)
+	}
+}
+
+func TestSessionsIsolatedAcrossCodes(t *testing.T) {
+	d := newDispatcher()
+	ctx := context.Background()
+	for _, want := range []string{"airtime 144/ n=1", "airtime 144/ n=2"} {
+		rep, _ := d.Handle(ctx, core.Request{SessionID: "s1", ServiceCode: "*144#", Text: "1"})
+		if rep.Message != want {
+			t.Fatalf("airtime: %q, want %q", rep.Message, want)
+		}
+	}
+	// same session ID, other code: a fresh session in its own namespace
+	rep, _ := d.Handle(ctx, core.Request{SessionID: "s1", ServiceCode: "*555#"})
+	if rep.Message != "wallet 555/ n=1" {
+		t.Fatalf("wallet: %q, want a fresh session", rep.Message)
+	}
+	rep, _ = d.Handle(ctx, core.Request{SessionID: "s1", ServiceCode: "*144#", Text: "1"})
+	if rep.Message != "airtime 144/ n=3" {
+		t.Fatalf("airtime after wallet: %q", rep.Message)
+	}
+}
+
+func TestAddWhileServing(t *testing.T) {
+	d := newDispatcher()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_, _ = d.Handle(context.Background(), core.Request{SessionID: fmt.Sprint(i, "-", j), ServiceCode: "*144#"})
+			}
+		}(i)
+	}
+	for j := 0; j < 50; j++ {
+		d.Add(Service{Code: fmt.Sprintf("*%d#", 600+j), App: counter("new")})
+	}
+	wg.Wait()
+	if rep, _ := d.Handle(context.Background(), core.Request{SessionID: "x", ServiceCode: "*649#"}); rep.Message != "new 649/ n=1" {
+		t.Fatalf("added service: %q", rep.Message)
+	}
+}
@@ -15,7 +15,7 @@ import (
 // Attach mounts the emulator UI and API onto your mux.
 // GET  /emu        -> HTML UI
 // POST /emu/send   -> {sessionId, msisdn, text, append} -> {raw, continue, message}
-func Attach(mux *http.ServeMux, eng *core.Engine) {
+func Attach(mux *http.ServeMux, eng core.Handler) {
 	mux.HandleFunc("/emu", func(w http.ResponseWriter, r *http.Request) {
 		_ = pageTmpl.Execute(w, map[string]any{
 			"RandSID":    "sess-" + strconv.FormatInt(time.Now().UnixNano(), 36),
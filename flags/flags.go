@@ -0,0 +1,149 @@
+// Package flags is a runtime-toggleable feature flag registry.
+//
+// Flags can be switched on/off, rolled out to a percentage of MSISDNs or
+// targeted at specific numbers without redeploying. Wire a Registry into a
+// router with rt.UseFlags(reg) to read flags via c.Flag("name"), and mount
+// reg.Handler() behind your admin auth to toggle them over HTTP.
+package flags
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Flag is a single feature switch.
+type Flag struct {
+	Name string `json:"name"`
+	On   bool   `json:"on"`
+	// Percent rolls the flag out to a stable share (1-100) of MSISDNs.
+	// With Percent 0 and no MSISDNs, an "on" flag applies to everyone.
+	Percent int `json:"percent,omitempty"`
+	// MSISDNs always get the flag while it is on (leading "+" ignored).
+	MSISDNs []string `json:"msisdns,omitempty"`
+}
+
+// Enabled reports whether the flag applies to msisdn.
+func (f Flag) Enabled(msisdn string) bool {
+	if !f.On {
+		return false
+	}
+	if f.Percent == 0 && len(f.MSISDNs) == 0 {
+		return true
+	}
+	m := strings.TrimPrefix(msisdn, "+")
+	for _, x := range f.MSISDNs {
+		if strings.TrimPrefix(x, "+") == m {
+			return true
+		}
+	}
+	if f.Percent <= 0 || m == "" {
+		return false
+	}
+	return bucket(f.Name, m) < uint32(f.Percent)
+}
+
+// bucket maps (flag, msisdn) to 0..99 so each user keeps the same answer.
+func bucket(name, msisdn string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name + ":" + msisdn))
+	return h.Sum32() % 100
+}
+
+// Registry holds flags; it is safe for concurrent use.
+type Registry struct {
+	mu sync.RWMutex
+	m  map[string]Flag
+}
+
+func New(initial ...Flag) *Registry {
+	r := &Registry{m: map[string]Flag{}}
+	for _, f := range initial {
+		r.m[f.Name] = f
+	}
+	return r
+}
+
+func (r *Registry) Set(f Flag) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.m[f.Name] = f
+}
+
+// Enable turns name on for everyone, keeping no rollout rules.
+func (r *Registry) Enable(name string) { r.Set(Flag{Name: name, On: true}) }
+
+// Disable turns name off, keeping its rollout rules for the next Enable via Set.
+func (r *Registry) Disable(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f := r.m[name]
+	f.Name, f.On = name, false
+	r.m[name] = f
+}
+
+func (r *Registry) Delete(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.m, name)
+}
+
+func (r *Registry) Get(name string) (Flag, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f, ok := r.m[name]
+	return f, ok
+}
+
+// All returns every flag sorted by name.
+func (r *Registry) All() []Flag {
+	r.mu.RLock()
+	out := make([]Flag, 0, len(r.m))
+	for _, f := range r.m {
+		out = append(out, f)
+	}
+	r.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Enabled reports whether name is on for msisdn. Unknown flags are off.
+func (r *Registry) Enabled(name, msisdn string) bool {
+	f, ok := r.Get(name)
+	return ok && f.Enabled(msisdn)
+}
+
+// Handler is a small admin API. Protect it (auth, internal network) before mounting.
+//
+//	GET                     -> [Flag...]
+//	POST/PUT  {Flag JSON}   -> upsert
+//	DELETE    ?name=<flag>  -> remove
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+		case http.MethodPost, http.MethodPut:
+			var f Flag
+			if err := json.NewDecoder(req.Body).Decode(&f); err != nil || f.Name == "" {
+				http.Error(w, "bad json", http.StatusBadRequest)
+				return
+			}
+			r.Set(f)
+		case http.MethodDelete:
+			name := req.URL.Query().Get("name")
+			if name == "" {
+				http.Error(w, "missing name", http.StatusBadRequest)
+				return
+			}
+			r.Delete(name)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(r.All())
+	})
+}
@@ -0,0 +1,103 @@
+package flags
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFlagEnabled(t *testing.T) {
+	tests := []struct {
+		name   string
+		flag   Flag
+		msisdn string
+		want   bool
+	}{
+		{"off", Flag{Name: "f"}, "+258840000001", false},
+		{"on for everyone", Flag{Name: "f", On: true}, "+258840000001", true},
+		{"on for everyone, no msisdn", Flag{Name: "f", On: true}, "", true},
+		{"targeted", Flag{Name: "f", On: true, MSISDNs: []string{"+258840000001"}}, "+258840000001", true},
+		{"targeted without plus", Flag{Name: "f", On: true, MSISDNs: []string{"258840000001"}}, "+258840000001", true},
+		{"not targeted", Flag{Name: "f", On: true, MSISDNs: []string{"+258840000001"}}, "+258840000002", false},
+		{"targeted but off", Flag{Name: "f", MSISDNs: []string{"+258840000001"}}, "+258840000001", false},
+		{"percent 100", Flag{Name: "f", On: true, Percent: 100}, "+258840000002", true},
+		{"percent without msisdn", Flag{Name: "f", On: true, Percent: 100}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.flag.Enabled(tt.msisdn); got != tt.want {
+				t.Fatalf("Enabled(%q) = %v, want %v", tt.msisdn, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlagPercent(t *testing.T) {
+	f30 := Flag{Name: "new-menu", On: true, Percent: 30}
+	f50 := Flag{Name: "new-menu", On: true, Percent: 50}
+	n := 0
+	for i := 0; i < 2000; i++ {
+		m := fmt.Sprintf("+25884%07d", i)
+		on := f30.Enabled(m)
+		if on != f30.Enabled(m) {
+			t.Fatalf("%s: unstable answer", m)
+		}
+		if on && !f50.Enabled(m) {
+			t.Fatalf("%s: in the 30%% rollout but not the 50%% one", m)
+		}
+		if on {
+			n++
+		}
+	}
+	if n < 500 || n > 700 {
+		t.Fatalf("30%% rollout enabled %d of 2000", n)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	reg := New(Flag{Name: "a", On: true})
+	h := reg.Handler()
+	do := func(method, target, body string) (int, []Flag) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		var out []Flag
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code, out
+	}
+
+	tests := []struct {
+		method, target, body string
+		code                 int
+		want                 []Flag
+	}{
+		{http.MethodGet, "/", "", 200, []Flag{{Name: "a", On: true}}},
+		{http.MethodPost, "/", `{"name":"b","on":true,"percent":10}`, 200, []Flag{{Name: "a", On: true}, {Name: "b", On: true, Percent: 10}}},
+		{http.MethodPut, "/", `{"name":"a","on":false}`, 200, []Flag{{Name: "a"}, {Name: "b", On: true, Percent: 10}}},
+		{http.MethodPost, "/", `{"on":true}`, 400, nil},
+		{http.MethodPost, "/", `{`, 400, nil},
+		{http.MethodDelete, "/?name=a", "", 200, []Flag{{Name: "b", On: true, Percent: 10}}},
+		{http.MethodDelete, "/", "", 400, nil},
+		{http.MethodPatch, "/", "", 405, nil},
+	}
+	for _, tt := range tests {
+		code, got := do(tt.method, tt.target, tt.body)
+		if code != tt.code {
+			t.Fatalf("%s %s %s: status %d, want %d", tt.method, tt.target, tt.body, code, tt.code)
+		}
+		if code == http.StatusOK && !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%s %s %s: got %+v, want %+v", tt.method, tt.target, tt.body, got, tt.want)
+		}
+	}
+	if reg.Enabled("b", "+258840000001") != (Flag{Name: "b", On: true, Percent: 10}).Enabled("+258840000001") {
+		t.Fatal("registry disagrees with the stored flag")
+	}
+}
@@ -0,0 +1,71 @@
+package load
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/grahms/cardinal/core"
+)
+
+// HTTPTarget posts each step to a transport endpoint as a form with the
+// Africa's Talking field names (also understood by transport.HTTPHandler) and
+// reads the "CON ..."/"END ..." reply. It implements core.Handler, so it plugs
+// into Config.Target like an in-process engine.
+type HTTPTarget struct {
+	URL    string
+	Client *http.Client // default http.DefaultClient
+
+	// Fields overrides form field names: keys "sessionId", "phoneNumber",
+	// "serviceCode", "text".
+	Fields map[string]string
+}
+
+var _ core.Handler = (*HTTPTarget)(nil)
+
+func (t *HTTPTarget) Handle(ctx context.Context, req core.Request) (core.Reply, error) {
+	name := func(k string) string {
+		if v := t.Fields[k]; v != "" {
+			return v
+		}
+		return k
+	}
+	form := url.Values{
+		name("sessionId"):   {req.SessionID},
+		name("phoneNumber"): {req.Msisdn},
+		name("serviceCode"): {req.ServiceCode},
+		name("text"):        {req.Text},
+	}
+	hr, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return core.Reply{}, err
+	}
+	hr.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	cl := t.Client
+	if cl == nil {
+		cl = http.DefaultClient
+	}
+	res, err := cl.Do(hr)
+	if err != nil {
+		return core.Reply{}, err
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+	if err != nil {
+		return core.Reply{}, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return core.Reply{}, fmt.Errorf("http %d", res.StatusCode)
+	}
+	body := string(b)
+	switch {
+	case strings.HasPrefix(body, "CON "):
+		return core.CON(body[4:]), nil
+	case strings.HasPrefix(body, "END "):
+		return core.END(body[4:]), nil
+	}
+	return core.Reply{}, fmt.Errorf("reply without CON/END prefix")
+}
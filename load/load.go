@@ -0,0 +1,446 @@
+// Package load runs many virtual users through scripted USSD flows to measure
+// how a node behaves under concurrent dialogues.
+//
+// The target is any core.Handler: a *core.Engine in-process, or an HTTP
+// transport endpoint through HTTPTarget. Flows use the same JSON shape as
+// testkit scenarios, so QA scripts double as load scripts.
+package load
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+)
+
+// Step is one exchange, with the same fields as testkit.Step. An empty Input
+// checks the current screen without sending (typically the first step, after
+// the session opens).
+type Step struct {
+	Input  string `json:"input"`
+	Expect string `json:"expect"` // substring the next screen must contain
+	Match  string `json:"match"`  // regexp the next screen must match
+	End    bool   `json:"end"`    // the session must end here
+}
+
+// Flow is a scripted dialogue; Weight picks flows proportionally (default 1).
+// If Msisdn is set every session of the flow dials from it; otherwise each
+// session gets a random number under Config.MsisdnPrefix.
+type Flow struct {
+	Name        string `json:"name"`
+	Msisdn      string `json:"msisdn"`
+	ServiceCode string `json:"serviceCode"`
+	Steps       []Step `json:"steps"`
+	Weight      int    `json:"weight"`
+}
+
+// LoadFlows reads one flow or a list of flows from a JSON file
+// (testkit scenario files work as-is).
+func LoadFlows(path string) ([]Flow, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var list []Flow
+	if err := json.Unmarshal(b, &list); err != nil {
+		var one Flow
+		if err2 := json.Unmarshal(b, &one); err2 != nil {
+			return nil, fmt.Errorf("load: %s: %w", path, err2)
+		}
+		list = []Flow{one}
+	}
+	for i := range list {
+		if list[i].Name == "" {
+			list[i].Name = fmt.Sprintf("%s#%d", path, i+1)
+		}
+	}
+	return list, nil
+}
+
+// Config describes a run. Zero values get defaults.
+type Config struct {
+	Target core.Handler
+	Flows  []Flow
+
+	Users    int           // concurrent virtual users (default 10)
+	Rate     float64       // new sessions per second; 0 starts one whenever a user is free
+	Duration time.Duration // stop starting sessions after this long (default 10s)
+	Sessions int           // or after this many sessions (0 = no limit)
+
+	ThinkTime   time.Duration // pause before each input
+	ThinkJitter time.Duration // random extra pause, up to this much
+	StepTimeout time.Duration // a slower step counts as a timeout (default 5s)
+
+	Accumulate   bool   // send "1*100*1" like gateways that accumulate input
+	MsisdnPrefix string // virtual users dial from prefix + 7 digits (default "+25884")
+	Seed         int64  // for flow choice, think jitter and MSISDNs (default 1)
+}
+
+// Run drives cfg.Target until Duration elapses, Sessions have started or ctx
+// is cancelled, then waits for in-flight sessions and reports.
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	if cfg.Target == nil {
+		return nil, errors.New("load: no target")
+	}
+	if len(cfg.Flows) == 0 {
+		return nil, errors.New("load: no flows")
+	}
+	if cfg.Users <= 0 {
+		cfg.Users = 10
+	}
+	if cfg.Duration <= 0 {
+		cfg.Duration = 10 * time.Second
+	}
+	if cfg.StepTimeout <= 0 {
+		cfg.StepTimeout = 5 * time.Second
+	}
+	if cfg.MsisdnPrefix == "" {
+		cfg.MsisdnPrefix = "+25884"
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = 1
+	}
+
+	r := &runner{
+		cfg:   cfg,
+		rnd:   rand.New(rand.NewSource(cfg.Seed)),
+		runID: strconv.FormatInt(time.Now().UnixNano(), 36), // distinct sessions per run against a live node
+		match: map[string]*regexp.Regexp{},
+	}
+	for _, f := range cfg.Flows {
+		for i, st := range f.Steps {
+			if st.Match == "" || r.match[st.Match] != nil {
+				continue
+			}
+			re, err := regexp.Compile(st.Match)
+			if err != nil {
+				return nil, fmt.Errorf("load: %s step %d: bad match: %w", f.Name, i+1, err)
+			}
+			r.match[st.Match] = re
+		}
+		w := f.Weight
+		if w <= 0 {
+			w = 1
+		}
+		r.total += w
+	}
+
+	stopAt := time.Now().Add(cfg.Duration)
+	runCtx, cancel := context.WithDeadline(ctx, stopAt)
+	defer cancel()
+
+	var tick <-chan time.Time
+	if cfg.Rate > 0 {
+		t := time.NewTicker(time.Duration(float64(time.Second) / cfg.Rate))
+		defer t.Stop()
+		tick = t.C
+	}
+
+	start := time.Now()
+	slots := make(chan struct{}, cfg.Users)
+	var wg sync.WaitGroup
+	for n := 0; cfg.Sessions == 0 || n < cfg.Sessions; n++ {
+		if tick != nil {
+			select {
+			case <-tick:
+			case <-runCtx.Done():
+			}
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-runCtx.Done():
+		}
+		if runCtx.Err() != nil {
+			break
+		}
+		flow, msisdn := r.pick()
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			// sessions finish even after the run deadline; only ctx aborts them
+			r.session(ctx, n, flow, msisdn)
+		}(n)
+	}
+	wg.Wait()
+	return r.report(time.Since(start)), nil
+}
+
+type runner struct {
+	cfg   Config
+	total int
+	match map[string]*regexp.Regexp // compiled Step.Match patterns
+
+	mu  sync.Mutex // guards rnd and the samples below
+	rnd *rand.Rand
+
+	lat        []time.Duration
+	sessions   int
+	completed  int
+	steps      int64
+	errs       int
+	timeouts   int
+	mismatches int
+	failures   map[string]int
+	inflight   atomic.Int64
+	maxInfl    int64
+	runID      string
+}
+
+func (r *runner) pick() (Flow, string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := r.rnd.Intn(r.total)
+	flow := r.cfg.Flows[len(r.cfg.Flows)-1]
+	for _, f := range r.cfg.Flows {
+		w := f.Weight
+		if w <= 0 {
+			w = 1
+		}
+		if n < w {
+			flow = f
+			break
+		}
+		n -= w
+	}
+	msisdn := fmt.Sprintf("%s%07d", r.cfg.MsisdnPrefix, r.rnd.Intn(10_000_000))
+	if flow.Msisdn != "" {
+		msisdn = flow.Msisdn
+	}
+	return flow, msisdn
+}
+
+func (r *runner) think() time.Duration {
+	d := r.cfg.ThinkTime
+	if r.cfg.ThinkJitter > 0 {
+		r.mu.Lock()
+		d += time.Duration(r.rnd.Int63n(int64(r.cfg.ThinkJitter)))
+		r.mu.Unlock()
+	}
+	return d
+}
+
+// outcome of one session
+type outcome int
+
+const (
+	ok outcome = iota
+	failed
+	timedOut
+	mismatched
+)
+
+func (r *runner) session(ctx context.Context, n int, flow Flow, msisdn string) {
+	c := r.inflight.Add(1)
+	defer r.inflight.Add(-1)
+	r.mu.Lock()
+	if c > r.maxInfl {
+		r.maxInfl = c
+	}
+	r.mu.Unlock()
+
+	sid := fmt.Sprintf("load-%s-%d", r.runID, n+1)
+	req := core.Request{SessionID: sid, Msisdn: msisdn, ServiceCode: flow.ServiceCode, Kind: core.KindBegin}
+	rep, res, why := r.step(ctx, req)
+	var hist []string
+	for _, st := range flow.Steps {
+		if res != ok {
+			break
+		}
+		if st.Input != "" {
+			if !rep.Continue {
+				res, why = mismatched, fmt.Sprintf("%s: session ended before input %q", flow.Name, st.Input)
+				break
+			}
+			if d := r.think(); d > 0 {
+				select {
+				case <-time.After(d):
+				case <-ctx.Done():
+					return
+				}
+			}
+			hist = append(hist, st.Input)
+			text := st.Input
+			if r.cfg.Accumulate {
+				text = strings.Join(hist, "*")
+			}
+			req.Kind, req.Text = core.KindContinue, text
+			rep, res, why = r.step(ctx, req)
+			if res != ok {
+				break
+			}
+		}
+		switch {
+		case st.End && rep.Continue:
+			res, why = mismatched, fmt.Sprintf("%s: expected END after %q", flow.Name, st.Input)
+		case st.Expect != "" && !strings.Contains(rep.Message, st.Expect):
+			res, why = mismatched, fmt.Sprintf("%s: expected %q after %q", flow.Name, st.Expect, st.Input)
+		case st.Match != "" && !r.match[st.Match].MatchString(rep.Message):
+			res, why = mismatched, fmt.Sprintf("%s: expected match %q after %q", flow.Name, st.Match, st.Input)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions++
+	switch res {
+	case ok:
+		r.completed++
+	case failed:
+		r.errs++
+	case timedOut:
+		r.timeouts++
+	case mismatched:
+		r.mismatches++
+	}
+	if why != "" {
+		if r.failures == nil {
+			r.failures = map[string]int{}
+		}
+		r.failures[why]++
+	}
+}
+
+func (r *runner) step(ctx context.Context, req core.Request) (core.Reply, outcome, string) {
+	sctx, cancel := context.WithTimeout(ctx, r.cfg.StepTimeout)
+	defer cancel()
+	t0 := time.Now()
+	rep, err := r.cfg.Target.Handle(sctx, req)
+	d := time.Since(t0)
+
+	r.mu.Lock()
+	r.lat = append(r.lat, d)
+	r.steps++
+	r.mu.Unlock()
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || d > r.cfg.StepTimeout:
+		return rep, timedOut, "step timed out"
+	case err != nil:
+		return rep, failed, err.Error()
+	}
+	return rep, ok, ""
+}
+
+func (r *runner) report(elapsed time.Duration) *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rep := &Report{
+		Elapsed:     elapsed,
+		Sessions:    r.sessions,
+		Completed:   r.completed,
+		Errors:      r.errs,
+		Timeouts:    r.timeouts,
+		Mismatches:  r.mismatches,
+		Steps:       int(r.steps),
+		MaxInFlight: int(r.maxInfl),
+		Failures:    r.failures,
+	}
+	if s := elapsed.Seconds(); s > 0 {
+		rep.StepsPerSec = float64(r.steps) / s
+		rep.SessionsPerSec = float64(r.sessions) / s
+	}
+	rep.Latency = percentiles(r.lat)
+	return rep
+}
+
+// Latency summarizes step latencies.
+type Latency struct {
+	Min, Mean, P50, P90, P95, P99, Max time.Duration
+}
+
+func percentiles(xs []time.Duration) Latency {
+	if len(xs) == 0 {
+		return Latency{}
+	}
+	s := append([]time.Duration(nil), xs...)
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	var sum time.Duration
+	for _, x := range s {
+		sum += x
+	}
+	at := func(p float64) time.Duration {
+		i := int(p*float64(len(s))+0.5) - 1
+		if i < 0 {
+			i = 0
+		}
+		if i >= len(s) {
+			i = len(s) - 1
+		}
+		return s[i]
+	}
+	return Latency{
+		Min: s[0], Max: s[len(s)-1], Mean: sum / time.Duration(len(s)),
+		P50: at(.50), P90: at(.90), P95: at(.95), P99: at(.99),
+	}
+}
+
+// Report is the outcome of a run.
+type Report struct {
+	Elapsed     time.Duration
+	Sessions    int // sessions started and finished
+	Completed   int // sessions that played their flow to the end as scripted
+	Errors      int // sessions aborted by a transport or engine error
+	Timeouts    int // sessions aborted by a step slower than StepTimeout
+	Mismatches  int // sessions whose screens did not match the script
+	Steps       int // requests sent
+	MaxInFlight int // peak concurrent sessions
+
+	StepsPerSec    float64
+	SessionsPerSec float64
+	Latency        Latency
+
+	Failures map[string]int // failure reason -> sessions
+}
+
+// ErrorRate is the share of sessions that failed for any reason but timeouts.
+func (r *Report) ErrorRate() float64 { return r.rate(r.Errors + r.Mismatches) }
+
+// TimeoutRate is the share of sessions that hit StepTimeout.
+func (r *Report) TimeoutRate() float64 { return r.rate(r.Timeouts) }
+
+func (r *Report) rate(n int) float64 {
+	if r.Sessions == 0 {
+		return 0
+	}
+	return float64(n) / float64(r.Sessions)
+}
+
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "sessions:    %d in %s (%.1f/s), %d completed, peak %d concurrent\n",
+		r.Sessions, r.Elapsed.Round(time.Millisecond), r.SessionsPerSec, r.Completed, r.MaxInFlight)
+	fmt.Fprintf(&b, "steps:       %d (%.1f/s)\n", r.Steps, r.StepsPerSec)
+	l := r.Latency
+	fmt.Fprintf(&b, "latency:     min %s  mean %s  p50 %s  p90 %s  p95 %s  p99 %s  max %s\n",
+		l.Min, l.Mean, l.P50, l.P90, l.P95, l.P99, l.Max)
+	fmt.Fprintf(&b, "errors:      %d + %d screen mismatches (%.2f%%)\n", r.Errors, r.Mismatches, 100*r.ErrorRate())
+	fmt.Fprintf(&b, "timeouts:    %d (%.2f%%)\n", r.Timeouts, 100*r.TimeoutRate())
+	if len(r.Failures) > 0 {
+		reasons := make([]string, 0, len(r.Failures))
+		for k := range r.Failures {
+			reasons = append(reasons, k)
+		}
+		sort.Slice(reasons, func(i, j int) bool { return r.Failures[reasons[i]] > r.Failures[reasons[j]] })
+		b.WriteString("failures:\n")
+		for i, k := range reasons {
+			if i == 10 {
+				fmt.Fprintf(&b, "  ... %d more\n", len(reasons)-i)
+				break
+			}
+			fmt.Fprintf(&b, "  %6d  %s\n", r.Failures[k], k)
+		}
+	}
+	return b.String()
+}
@@ -57,19 +57,17 @@ func (b *Builder) WithExitLabel(s string) *Builder {
 
 // Prompt returns a CON reply with the built screen.
 func (b *Builder) Prompt(c *router.Ctx) core.Reply {
+	return b.prompt(c, b.title)
+}
+
+func (b *Builder) prompt(c *router.Ctx, title string) core.Reply {
 	var lines []string
-	if b.title != "" {
-		lines = append(lines, b.title)
-	}
-	for i, it := range b.items {
-		lines = append(lines, fmt.Sprintf("%d) %s", i+1, it.Label))
-	}
-	if b.backTo != "" {
-		lines = append(lines, "0) "+b.backLabel)
-	}
-	if b.exitTx != "" {
-		lines = append(lines, "00) "+b.exitLabel)
+	if title != "" {
+		lines = append(lines, title)
 	}
+	opts := b.options()
+	lines = append(lines, opts...)
+	c.OfferOptions(opts...)
 
 	return core.CON(strings.Join(lines, "\n"))
 }
@@ -82,15 +80,18 @@ func (b *Builder) Handle(c *router.Ctx) core.Reply {
 	}
 
 	if in == "0" && b.backTo != "" {
+		c.HitOption("0) " + b.backLabel)
 		c.Redirect(b.backTo)
 		return core.CON("") // engine will SHOW next
 	}
 	if in == "00" && b.exitTx != "" {
+		c.HitOption("00) " + b.exitLabel)
 		return core.END(b.exitTx)
 	}
 	idx, ok := atoi(in)
 	if ok && idx >= 1 && idx <= len(b.items) {
 		it := b.items[idx-1]
+		c.HitOption(fmt.Sprintf("%d) %s", idx, it.Label))
 		if it.Before != nil {
 			if err := it.Before(c); err != nil {
 				return core.END("Serviço indisponível. Tente mais tarde.")
@@ -104,14 +105,28 @@ func (b *Builder) Handle(c *router.Ctx) core.Reply {
 			return core.CON("")
 		}
 	}
-	old := b.title
-	if old != "" {
-		b.title = old + "\n⚠️ Opção inválida."
-		defer func() { b.title = old }()
+	// builders are often shared across sessions, so never mutate b here
+	if b.title != "" {
+		return b.prompt(c, b.title+"\n⚠️ Opção inválida.")
 	}
 	return b.Prompt(c)
 }
 
+// options renders the numbered option lines; they double as coverage labels.
+func (b *Builder) options() []string {
+	var out []string
+	for i, it := range b.items {
+		out = append(out, fmt.Sprintf("%d) %s", i+1, it.Label))
+	}
+	if b.backTo != "" {
+		out = append(out, "0) "+b.backLabel)
+	}
+	if b.exitTx != "" {
+		out = append(out, "00) "+b.exitLabel)
+	}
+	return out
+}
+
 func atoi(s string) (int, bool) {
 	n := 0
 	for _, r := range s {
@@ -0,0 +1,215 @@
+package middleware
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/router"
+)
+
+// TimeWindow is a daily opening window in local time. From/To are offsets
+// since midnight (e.g. 8*time.Hour); From > To wraps past midnight.
+// Empty Days means every day.
+type TimeWindow struct {
+	Days []time.Weekday
+	From time.Duration
+	To   time.Duration
+}
+
+func (w TimeWindow) contains(t time.Time) bool {
+	if len(w.Days) > 0 {
+		ok := false
+		for _, d := range w.Days {
+			if d == t.Weekday() {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	y, m, d := t.Date()
+	off := t.Sub(time.Date(y, m, d, 0, 0, 0, 0, t.Location()))
+	if w.From <= w.To {
+		return off >= w.From && off < w.To
+	}
+	return off >= w.From || off < w.To
+}
+
+// ACLConfig configures an ACL.
+//
+// MSISDN entries match exactly, or as a prefix when they end in "*"
+// ("+25884*"). A leading "+" is ignored on both sides. Deny wins over Allow;
+// an empty allow list admits everyone not denied.
+type ACLConfig struct {
+	Allow     []string
+	Deny      []string
+	AllowFile string // one entry per line, "#" comments; re-read by Reload
+	DenyFile  string
+
+	ServiceCodes []string       // if set, only these service codes pass
+	Hours        []TimeWindow   // if set, requests outside every window are closed
+	Location     *time.Location // for Hours (default time.Local)
+
+	Denied core.Reply // default END "Service not available."
+	Closed core.Reply // outside Hours (default END "Service closed. Please try later.")
+}
+
+// ACL is an allow/deny list middleware with optional service-code and
+// time-of-day rules. Lists loaded from files can be swapped at runtime with Reload.
+type ACL struct {
+	cfg ACLConfig
+
+	mu    sync.RWMutex
+	allow msisdnSet
+	deny  msisdnSet
+}
+
+// NewACL builds an ACL and loads its list files.
+func NewACL(cfg ACLConfig) (*ACL, error) {
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+	if cfg.Denied.Message == "" {
+		cfg.Denied = core.END("Service not available.")
+	}
+	if cfg.Closed.Message == "" {
+		cfg.Closed = core.END("Service closed. Please try later.")
+	}
+	a := &ACL{cfg: cfg}
+	if err := a.Reload(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Reload re-reads AllowFile/DenyFile. On error the previous lists stay active.
+func (a *ACL) Reload() error {
+	allow, err := buildSet(a.cfg.Allow, a.cfg.AllowFile)
+	if err != nil {
+		return err
+	}
+	deny, err := buildSet(a.cfg.Deny, a.cfg.DenyFile)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.allow, a.deny = allow, deny
+	a.mu.Unlock()
+	return nil
+}
+
+// Check reports whether the request may proceed, and the reply to show if not.
+func (a *ACL) Check(c *router.Ctx) (core.Reply, bool) {
+	a.mu.RLock()
+	denied := a.deny.has(c.Req.Msisdn)
+	allowed := a.allow.empty() || a.allow.has(c.Req.Msisdn)
+	a.mu.RUnlock()
+	if denied || !allowed {
+		return a.cfg.Denied, false
+	}
+	if len(a.cfg.ServiceCodes) > 0 && !contains(a.cfg.ServiceCodes, c.Req.ServiceCode) {
+		return a.cfg.Denied, false
+	}
+	if len(a.cfg.Hours) > 0 {
+		now := core.Now(c).In(a.cfg.Location)
+		open := false
+		for _, w := range a.cfg.Hours {
+			if w.contains(now) {
+				open = true
+				break
+			}
+		}
+		if !open {
+			return a.cfg.Closed, false
+		}
+	}
+	return core.Reply{}, true
+}
+
+// Middleware returns the ACL as router middleware (global, group or per route).
+func (a *ACL) Middleware() router.Middleware {
+	return func(next router.Handler) router.Handler {
+		return func(c *router.Ctx) core.Reply {
+			if rep, ok := a.Check(c); !ok {
+				return rep
+			}
+			return next(c)
+		}
+	}
+}
+
+/* ---------- msisdn sets ---------- */
+
+type msisdnSet struct {
+	exact    map[string]struct{}
+	prefixes []string
+}
+
+func (s msisdnSet) empty() bool { return len(s.exact) == 0 && len(s.prefixes) == 0 }
+
+func (s msisdnSet) has(msisdn string) bool {
+	m := strings.TrimPrefix(strings.TrimSpace(msisdn), "+")
+	if m == "" {
+		return false
+	}
+	if _, ok := s.exact[m]; ok {
+		return true
+	}
+	for _, p := range s.prefixes {
+		if strings.HasPrefix(m, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *msisdnSet) add(entry string) {
+	e := strings.TrimPrefix(strings.TrimSpace(entry), "+")
+	if e == "" {
+		return
+	}
+	if strings.HasSuffix(e, "*") {
+		s.prefixes = append(s.prefixes, strings.TrimSuffix(e, "*"))
+		return
+	}
+	s.exact[e] = struct{}{}
+}
+
+func buildSet(inline []string, file string) (msisdnSet, error) {
+	s := msisdnSet{exact: map[string]struct{}{}}
+	for _, e := range inline {
+		s.add(e)
+	}
+	if file == "" {
+		return s, nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return s, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		s.add(line)
+	}
+	return s, sc.Err()
+}
+
+func contains(xs []string, x string) bool {
+	for _, v := range xs {
+		if v == x {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/router"
+)
+
+func aclCtx(ctx context.Context, msisdn, code string) *router.Ctx {
+	return &router.Ctx{Context: ctx, Req: core.Request{Msisdn: msisdn, ServiceCode: code}}
+}
+
+func TestACLLists(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    ACLConfig
+		msisdn string
+		want   bool
+	}{
+		{"empty lists admit all", ACLConfig{}, "+258840000001", true},
+		{"allowed", ACLConfig{Allow: []string{"+258840000001"}}, "+258840000001", true},
+		{"not allowed", ACLConfig{Allow: []string{"+258840000001"}}, "+258840000002", false},
+		{"plus ignored", ACLConfig{Allow: []string{"258840000001"}}, "+258840000001", true},
+		{"prefix", ACLConfig{Allow: []string{"+25884*"}}, "+258841234567", true},
+		{"prefix miss", ACLConfig{Allow: []string{"+25884*"}}, "+258821234567", false},
+		{"denied", ACLConfig{Deny: []string{"+258840000001"}}, "+258840000001", false},
+		{"deny over allow", ACLConfig{Allow: []string{"+258840000001"}, Deny: []string{"+258840000001"}}, "+258840000001", false},
+		{"deny prefix over allow", ACLConfig{Allow: []string{"+25884*"}, Deny: []string{"+2588400*"}}, "+258840000001", false},
+		{"allow prefix, deny other", ACLConfig{Allow: []string{"+25884*"}, Deny: []string{"+2588400*"}}, "+258841000001", true},
+		{"empty msisdn with allow list", ACLConfig{Allow: []string{"*"}}, "", false},
+		{"star admits all", ACLConfig{Allow: []string{"*"}}, "+1555", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewACL(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rep, ok := a.Check(aclCtx(context.Background(), tt.msisdn, ""))
+			if ok != tt.want {
+				t.Fatalf("Check(%q) = %v, want %v", tt.msisdn, ok, tt.want)
+			}
+			if !ok && rep.Message != "Service not available." {
+				t.Fatalf("reply = %q", rep.Message)
+			}
+		})
+	}
+}
+
+func TestACLServiceCodes(t *testing.T) {
+	a, _ := NewACL(ACLConfig{ServiceCodes: []string{"*144#"}})
+	if _, ok := a.Check(aclCtx(context.Background(), "+1", "*144#")); !ok {
+		t.Fatal("listed code denied")
+	}
+	if _, ok := a.Check(aclCtx(context.Background(), "+1", "*555#")); ok {
+		t.Fatal("unlisted code admitted")
+	}
+}
+
+func TestACLReload(t *testing.T) {
+	dir := t.TempDir()
+	deny := filepath.Join(dir, "deny.txt")
+	write := func(s string) {
+		if err := os.WriteFile(deny, []byte(s), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("# fraud\n+258840000001\n")
+	a, err := NewACL(ACLConfig{DenyFile: deny})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(msisdn string) bool {
+		_, ok := a.Check(aclCtx(context.Background(), msisdn, ""))
+		return ok
+	}
+	if check("+258840000001") || !check("+258840000002") {
+		t.Fatal("initial deny list not applied")
+	}
+
+	write("+258840000002 # moved\n+25882*\n")
+	if err := a.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if !check("+258840000001") || check("+258840000002") || check("+258821111111") {
+		t.Fatal("reloaded deny list not applied")
+	}
+
+	if err := os.Remove(deny); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Reload(); err == nil {
+		t.Fatal("Reload of a missing file succeeded")
+	}
+	if check("+258840000002") {
+		t.Fatal("failed Reload dropped the previous list")
+	}
+}
+
+func TestACLHours(t *testing.T) {
+	at := func(day, hh, mm int) time.Time { // 2024-01-01 is a Monday
+		return time.Date(2024, 1, day, hh, mm, 0, 0, time.UTC)
+	}
+	office := TimeWindow{Days: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}, From: 8 * time.Hour, To: 17 * time.Hour}
+	night := TimeWindow{From: 22 * time.Hour, To: 2 * time.Hour}
+	tests := []struct {
+		name  string
+		hours []TimeWindow
+		now   time.Time
+		want  bool
+	}{
+		{"office open", []TimeWindow{office}, at(1, 8, 0), true},
+		{"office before", []TimeWindow{office}, at(1, 7, 59), false},
+		{"office end is exclusive", []TimeWindow{office}, at(1, 17, 0), false},
+		{"office weekend", []TimeWindow{office}, at(6, 10, 0), false},
+		{"night before midnight", []TimeWindow{night}, at(1, 23, 30), true},
+		{"night after midnight", []TimeWindow{night}, at(2, 1, 59), true},
+		{"night closes", []TimeWindow{night}, at(2, 2, 0), false},
+		{"night midday", []TimeWindow{night}, at(2, 12, 0), false},
+		{"either window", []TimeWindow{office, night}, at(6, 23, 0), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := NewACL(ACLConfig{Hours: tt.hours, Location: time.UTC})
+			ctx := core.WithClock(context.Background(), &testClock{t: tt.now})
+			rep, ok := a.Check(aclCtx(ctx, "+1", ""))
+			if ok != tt.want {
+				t.Fatalf("open at %s = %v, want %v", tt.now.Format("Mon 15:04"), ok, tt.want)
+			}
+			if !ok && rep.Message != "Service closed. Please try later." {
+				t.Fatalf("reply = %q", rep.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package middleware
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/router"
+)
+
+// ErrBreakerOpen is returned by Breaker.Do while the breaker rejects calls.
+var ErrBreakerOpen = errors.New("circuit breaker open")
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+const (
+	StateClosed   BreakerState = iota // calls flow, failures are counted
+	StateOpen                         // calls are rejected until Cooldown elapses
+	StateHalfOpen                     // a few trial calls decide whether to close again
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerConfig tunes breakers created by NewBreaker / NewBreakers.
+type BreakerConfig struct {
+	Failures    int           // consecutive failures that open the breaker (default 5)
+	Cooldown    time.Duration // time spent open before trying again (default 30s)
+	HalfOpenMax int           // concurrent trial calls while half-open (default 1)
+
+	// Degraded is the screen shown by CircuitBreaker while open
+	// (default END "Service temporarily unavailable. Please try later.").
+	Degraded core.Reply
+	// Failed classifies handler replies for CircuitBreaker. Panics always count.
+	// Default: the framework's END "Service unavailable." fallback.
+	Failed func(core.Reply) bool
+	// OnStateChange is called (outside the lock) on every transition, e.g. to export metrics.
+	OnStateChange func(name string, from, to BreakerState)
+}
+
+func (cfg *BreakerConfig) defaults() {
+	if cfg.Failures <= 0 {
+		cfg.Failures = 5
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+	if cfg.HalfOpenMax <= 0 {
+		cfg.HalfOpenMax = 1
+	}
+	if cfg.Degraded.Message == "" {
+		cfg.Degraded = core.END("Service temporarily unavailable. Please try later.")
+	}
+	if cfg.Failed == nil {
+		cfg.Failed = func(r core.Reply) bool {
+			return !r.Continue && r.Message == "Service unavailable."
+		}
+	}
+}
+
+// Breaker is a closed/open/half-open circuit breaker.
+type Breaker struct {
+	name string
+	cfg  BreakerConfig
+
+	mu       sync.Mutex
+	state    BreakerState
+	gen      uint64 // bumped on every transition; stale outcomes are dropped
+	fails    int
+	openedAt time.Time
+	trials   int
+}
+
+// NewBreaker creates a standalone breaker, e.g. to guard menu.Item.Before hooks.
+func NewBreaker(name string, cfg BreakerConfig) *Breaker {
+	cfg.defaults()
+	return &Breaker{name: name, cfg: cfg}
+}
+
+func (b *Breaker) Name() string { return b.name }
+
+// State reports the current state (an expired open breaker reads as half-open).
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == StateOpen && time.Since(b.openedAt) >= b.cfg.Cooldown {
+		return StateHalfOpen
+	}
+	return b.state
+}
+
+// Do runs fn unless the breaker is open, and records its outcome.
+func (b *Breaker) Do(fn func() error) error {
+	gen, ok := b.acquire()
+	if !ok {
+		return ErrBreakerOpen
+	}
+	err := fn()
+	b.record(gen, err == nil)
+	return err
+}
+
+// Hook wraps a menu.Item.Before-style hook so backend failures trip the breaker.
+//
+//	billing := middleware.NewBreaker("billing", middleware.BreakerConfig{})
+//	menu.New("/home").Opt("Balance", "/balance", billing.Hook(loadBalance))
+func (b *Breaker) Hook(fn func(*router.Ctx) error) func(*router.Ctx) error {
+	return func(c *router.Ctx) error {
+		return b.Do(func() error { return fn(c) })
+	}
+}
+
+// acquire admits a call and returns the generation it belongs to.
+func (b *Breaker) acquire() (uint64, bool) {
+	b.mu.Lock()
+	from := b.state
+	ok := true
+	switch b.state {
+	case StateOpen:
+		if time.Since(b.openedAt) < b.cfg.Cooldown {
+			ok = false
+			break
+		}
+		b.set(StateHalfOpen)
+		fallthrough
+	case StateHalfOpen:
+		if b.trials >= b.cfg.HalfOpenMax {
+			ok = false
+			break
+		}
+		b.trials++
+	}
+	gen, to := b.gen, b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+	return gen, ok
+}
+
+// record applies the outcome of a call acquired in generation gen. Outcomes
+// from an older generation are ignored, so a slow call admitted while closed
+// cannot close a breaker that opened in the meantime.
+func (b *Breaker) record(gen uint64, success bool) {
+	b.mu.Lock()
+	if gen != b.gen {
+		b.mu.Unlock()
+		return
+	}
+	from := b.state
+	switch {
+	case success:
+		b.fails = 0
+		b.set(StateClosed)
+	case b.state == StateHalfOpen:
+		b.set(StateOpen)
+	default:
+		b.fails++
+		if b.fails >= b.cfg.Failures {
+			b.set(StateOpen)
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+}
+
+// set moves to state, starting a new generation if it changed. Callers hold mu.
+func (b *Breaker) set(state BreakerState) {
+	if state == b.state {
+		return
+	}
+	b.state, b.trials = state, 0
+	b.gen++
+	if state == StateOpen {
+		b.openedAt = time.Now()
+	}
+}
+
+func (b *Breaker) notify(from, to BreakerState) {
+	if from != to && b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(b.name, from, to)
+	}
+}
+
+// Breakers is a registry of named breakers sharing one config (per route, per key).
+type Breakers struct {
+	cfg BreakerConfig
+	mu  sync.Mutex
+	m   map[string]*Breaker
+}
+
+func NewBreakers(cfg BreakerConfig) *Breakers {
+	cfg.defaults()
+	return &Breakers{cfg: cfg, m: map[string]*Breaker{}}
+}
+
+// Get returns the breaker for name, creating it on first use.
+func (s *Breakers) Get(name string) *Breaker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.m[name]
+	if !ok {
+		b = NewBreaker(name, s.cfg)
+		s.m[name] = b
+	}
+	return b
+}
+
+// States snapshots every breaker's state, keyed by name (for metrics/health).
+func (s *Breakers) States() map[string]BreakerState {
+	s.mu.Lock()
+	bs := make([]*Breaker, 0, len(s.m))
+	for _, b := range s.m {
+		bs = append(bs, b)
+	}
+	s.mu.Unlock()
+	out := make(map[string]BreakerState, len(bs))
+	for _, b := range bs {
+		out[b.name] = b.State()
+	}
+	return out
+}
+
+// CircuitBreaker guards handlers with a breaker picked by key (nil: per route path).
+// While open, the degraded screen is returned without calling the handler.
+//
+// Only panics and replies matching BreakerConfig.Failed count as failures. The
+// default Failed matches nothing but the framework's own END "Service
+// unavailable." fallback, so a handler that renders its own error screen never
+// trips the breaker; set Failed to classify those replies.
+func CircuitBreaker(set *Breakers, key func(*router.Ctx) string) router.Middleware {
+	if key == nil {
+		key = func(c *router.Ctx) string { return c.Path() }
+	}
+	return func(next router.Handler) router.Handler {
+		return func(c *router.Ctx) (rep core.Reply) {
+			b := set.Get(key(c))
+			gen, ok := b.acquire()
+			if !ok {
+				return set.cfg.Degraded
+			}
+			defer func() {
+				if r := recover(); r != nil {
+					b.record(gen, false)
+					panic(r) // let Recover() render it
+				}
+				b.record(gen, !set.cfg.Failed(rep))
+			}()
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreakerIgnoresStaleOutcome(t *testing.T) {
+	b := NewBreaker("backend", BreakerConfig{Failures: 1, Cooldown: time.Hour})
+
+	slow, ok := b.acquire() // admitted while closed, finishes late
+	if !ok {
+		t.Fatal("closed breaker rejected a call")
+	}
+	if err := b.Do(func() error { return errors.New("down") }); err == nil {
+		t.Fatal("want backend error")
+	}
+	if s := b.State(); s != StateOpen {
+		t.Fatalf("state = %v, want open", s)
+	}
+
+	b.record(slow, true)
+	if s := b.State(); s != StateOpen {
+		t.Fatalf("stale success moved breaker to %v, want open", s)
+	}
+	if err := b.Do(func() error { return nil }); !errors.Is(err, ErrBreakerOpen) {
+		t.Fatalf("Do() = %v, want ErrBreakerOpen", err)
+	}
+}
+
+func TestBreakerHalfOpenTrial(t *testing.T) {
+	b := NewBreaker("backend", BreakerConfig{Failures: 1, Cooldown: time.Millisecond})
+	_ = b.Do(func() error { return errors.New("down") })
+	time.Sleep(2 * time.Millisecond)
+
+	trial, ok := b.acquire()
+	if !ok {
+		t.Fatal("expired breaker rejected the trial call")
+	}
+	if _, ok := b.acquire(); ok {
+		t.Fatal("half-open breaker admitted a second trial")
+	}
+	b.record(trial, true)
+	if s := b.State(); s != StateClosed {
+		t.Fatalf("state = %v, want closed", s)
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/router"
+)
+
+// Maintenance shows screen instead of the handler while flag is on for the
+// caller (f is usually a *flags.Registry). Attach it globally for a full
+// outage window or to a Group such as "/wallet/transfer" for a single branch;
+// toggling the flag takes effect on the next step, without a redeploy.
+func Maintenance(f router.FlagSource, flag string, screen core.Reply) router.Middleware {
+	if screen.Message == "" {
+		screen = core.END("Service under maintenance. Please try later.")
+	}
+	return func(next router.Handler) router.Handler {
+		return func(c *router.Ctx) core.Reply {
+			if f.Enabled(flag, c.Req.Msisdn) {
+				return screen
+			}
+			return next(c)
+		}
+	}
+}
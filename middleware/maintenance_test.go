@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/flags"
+	"github.com/grahms/cardinal/router"
+)
+
+func TestMaintenance(t *testing.T) {
+	reg := flags.New()
+	next := func(c *router.Ctx) core.Reply { return core.CON("Menu") }
+	tests := []struct {
+		name   string
+		flag   *flags.Flag
+		screen core.Reply
+		msisdn string
+		want   string
+	}{
+		{"unknown flag", nil, core.Reply{}, "+258840000001", "Menu"},
+		{"off", &flags.Flag{Name: "maint"}, core.Reply{}, "+258840000001", "Menu"},
+		{"on", &flags.Flag{Name: "maint", On: true}, core.Reply{}, "+258840000001", "Service under maintenance. Please try later."},
+		{"custom screen", &flags.Flag{Name: "maint", On: true}, core.END("Back at 6"), "+258840000001", "Back at 6"},
+		{"targeted", &flags.Flag{Name: "maint", On: true, MSISDNs: []string{"+258840000001"}}, core.Reply{}, "+258840000001", "Service under maintenance. Please try later."},
+		{"not targeted", &flags.Flag{Name: "maint", On: true, MSISDNs: []string{"+258840000001"}}, core.Reply{}, "+258840000002", "Menu"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg.Delete("maint")
+			if tt.flag != nil {
+				reg.Set(*tt.flag)
+			}
+			h := Maintenance(reg, "maint", tt.screen)(next)
+			rep := h(&router.Ctx{Context: context.Background(), Req: core.Request{Msisdn: tt.msisdn}})
+			if rep.Message != tt.want {
+				t.Fatalf("got %q, want %q", rep.Message, tt.want)
+			}
+		})
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"log"
-	"sync"
 	"time"
 
 	"github.com/grahms/cardinal/core"
@@ -78,48 +77,8 @@ func TightRouteLimit() router.Middleware {
 	return lim
 }
 
-// RateLimitPerMSISDN: simple token-bucket per phone number.
-// allow N requests per window (rough approximation, reset on window).
+// RateLimitPerMSISDN: sliding-window limit per phone number using an
+// in-memory backend. Use RateLimit for shared backends or other keys.
 func RateLimitPerMSISDN(limit int, window time.Duration) router.Middleware {
-	b := &bucket{
-		limit:  limit,
-		window: window,
-		m:      map[string]*entry{},
-	}
-	return func(next router.Handler) router.Handler {
-		return func(c *router.Ctx) core.Reply {
-			if !b.allow(c.Req.Msisdn) {
-				return core.END("Busy. Please try again.")
-			}
-			return next(c)
-		}
-	}
-}
-
-type entry struct {
-	cnt   int
-	reset time.Time
-}
-type bucket struct {
-	mu     sync.Mutex
-	m      map[string]*entry
-	limit  int
-	window time.Duration
-}
-
-func (b *bucket) allow(key string) bool {
-	now := time.Now()
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	e, ok := b.m[key]
-	if !ok || now.After(e.reset) {
-		b.m[key] = &entry{cnt: 1, reset: now.Add(b.window)}
-		return true
-	}
-	if e.cnt < b.limit {
-		e.cnt++
-		return true
-	}
-	return false
+	return RateLimit(RateLimitConfig{Limit: limit, Window: window})
 }
@@ -0,0 +1,223 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/router"
+)
+
+// RateBackend stores rate-limit counters. Implementations must be safe for
+// concurrent use; a shared backend (e.g. Redis INCR + PEXPIRE) makes limits
+// hold across replicas.
+type RateBackend interface {
+	// Incr adds one to key and returns the new value. ttl applies when the
+	// key is created.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// Count returns the current value of key (0 if missing or expired).
+	Count(ctx context.Context, key string) (int64, error)
+}
+
+// RateKey extracts the bucket key from a request. Empty keys are not limited.
+type RateKey func(c *router.Ctx) string
+
+// KeyMSISDN limits per phone number.
+func KeyMSISDN(c *router.Ctx) string { return c.Req.Msisdn }
+
+// KeyServiceCode limits per dialled service code (e.g. "*144#").
+func KeyServiceCode(c *router.Ctx) string { return c.Req.ServiceCode }
+
+// KeyRoute limits per phone number on the current route.
+func KeyRoute(c *router.Ctx) string { return c.Path() + "|" + c.Req.Msisdn }
+
+// RateLimitConfig configures RateLimit.
+type RateLimitConfig struct {
+	Limit   int           // max requests per window
+	Window  time.Duration // sliding window length
+	Backend RateBackend   // default: NewMemoryRateBackend()
+	Key     RateKey       // default: KeyMSISDN
+	Prefix  string        // backend key namespace (default "rl")
+	Reply   core.Reply    // reply when limited (default END "Busy. Please try again.")
+}
+
+// RateLimit is a sliding-window limiter. It keeps one counter per fixed window
+// and weights the previous window by how much of it still overlaps, which only
+// needs INCR/GET semantics from the backend.
+//
+// Backend errors fail open: a broken counter store should not take the service down.
+func RateLimit(cfg RateLimitConfig) router.Middleware {
+	if cfg.Backend == nil {
+		cfg.Backend = NewMemoryRateBackend()
+	}
+	if cfg.Key == nil {
+		cfg.Key = KeyMSISDN
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "rl"
+	}
+	if cfg.Reply.Message == "" {
+		cfg.Reply = core.END("Busy. Please try again.")
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = time.Second
+	}
+	return func(next router.Handler) router.Handler {
+		return func(c *router.Ctx) core.Reply {
+			k := cfg.Key(c)
+			if k == "" || cfg.Limit <= 0 {
+				return next(c)
+			}
+			if !slidingAllow(c, cfg, k) {
+				return cfg.Reply
+			}
+			return next(c)
+		}
+	}
+}
+
+func slidingAllow(ctx context.Context, cfg RateLimitConfig, k string) bool {
+	now := core.Now(ctx).UnixNano()
+	w := int64(cfg.Window)
+	idx := now / w
+	frac := float64(now%w) / float64(w)
+
+	base := cfg.Prefix + ":" + k + ":"
+	curr, err := cfg.Backend.Incr(ctx, base+strconv.FormatInt(idx, 10), 2*cfg.Window)
+	if err != nil {
+		return true
+	}
+	prev, err := cfg.Backend.Count(ctx, base+strconv.FormatInt(idx-1, 10))
+	if err != nil {
+		prev = 0
+	}
+	est := float64(prev)*(1-frac) + float64(curr)
+	return est <= float64(cfg.Limit)
+}
+
+/* ---------- in-memory backend ---------- */
+
+// MemoryRateBackend is a process-local RateBackend. Expired counters are
+// evicted lazily, so memory stays bounded by the number of active keys.
+// Time comes from core.Now, so a fake clock in the context drives expiry.
+type MemoryRateBackend struct {
+	mu        sync.Mutex
+	m         map[string]*counter
+	nextSweep time.Time
+}
+
+type counter struct {
+	n   int64
+	exp time.Time
+}
+
+func NewMemoryRateBackend() *MemoryRateBackend {
+	return &MemoryRateBackend{m: map[string]*counter{}}
+}
+
+func (b *MemoryRateBackend) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	now := core.Now(ctx)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sweep(now)
+
+	e, ok := b.m[key]
+	if !ok || now.After(e.exp) {
+		e = &counter{exp: now.Add(ttl)}
+		b.m[key] = e
+	}
+	e.n++
+	return e.n, nil
+}
+
+func (b *MemoryRateBackend) Count(ctx context.Context, key string) (int64, error) {
+	now := core.Now(ctx)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e, ok := b.m[key]
+	if !ok || now.After(e.exp) {
+		return 0, nil
+	}
+	return e.n, nil
+}
+
+// Len reports how many counters are held (including not-yet-swept expired ones).
+func (b *MemoryRateBackend) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.m)
+}
+
+func (b *MemoryRateBackend) sweep(now time.Time) {
+	if now.Before(b.nextSweep) {
+		return
+	}
+	for k, e := range b.m {
+		if now.After(e.exp) {
+			delete(b.m, k)
+		}
+	}
+	b.nextSweep = now.Add(time.Minute)
+}
+
+/* ---------- core.Store backend ---------- */
+
+// StoreRateBackend keeps counters in a core.Store, so the same store that
+// holds sessions (e.g. Redis) can share limits across replicas.
+//
+// Limits are only exact across replicas if the store implements core.Counter
+// (atomic increments). Otherwise Incr falls back to Get+Put, which is atomic
+// within this process but loses concurrent increments from other replicas,
+// so the limit is enforced loosely.
+type StoreRateBackend struct {
+	st core.Store
+	mu sync.Mutex // serializes the Get+Put fallback within this process
+}
+
+func NewStoreRateBackend(st core.Store) *StoreRateBackend {
+	return &StoreRateBackend{st: st}
+}
+
+func (b *StoreRateBackend) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	if c, ok := b.st.(core.Counter); ok {
+		return c.Incr(ctx, key, ttl)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n, err := b.Count(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	n++
+	if err := b.st.Put(ctx, key, map[string]any{"n": n}, ttl); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (b *StoreRateBackend) Count(ctx context.Context, key string) (int64, error) {
+	if c, ok := b.st.(core.Counter); ok {
+		return c.Count(ctx, key)
+	}
+	d, err := b.st.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	switch v := d["n"].(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case float64: // JSON-encoded stores
+		return int64(v), nil
+	}
+	return 0, nil
+}
+
+// Ensure interface conformance at compile-time.
+var (
+	_ RateBackend = (*MemoryRateBackend)(nil)
+	_ RateBackend = (*StoreRateBackend)(nil)
+)
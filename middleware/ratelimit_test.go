@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/store"
+)
+
+type testClock struct{ t time.Time }
+
+func (c *testClock) Now() time.Time { return c.t }
+
+func TestSlidingWindow(t *testing.T) {
+	const window = 10 * time.Second
+	clk := &testClock{t: time.Unix(0, 0).Add(1000 * window)} // start of a window
+	ctx := core.WithClock(context.Background(), clk)
+	cfg := RateLimitConfig{Limit: 3, Window: window, Backend: NewMemoryRateBackend(), Prefix: "rl"}
+
+	steps := []struct {
+		advance time.Duration
+		want    bool
+	}{
+		{0, true},
+		{0, true},
+		{0, true},
+		{0, false},                // 4 in the current window (denied requests count too)
+		{window + window/2, true}, // prev 4 weighted by 0.5, +1 = 3
+		{0, false},                // 4*0.5 + 2 = 4
+		{window / 4, false},       // 4*0.25 + 3 = 4
+		{window / 4, false},       // new window: prev 4 weighted by 1 = 5
+		{3 * window, true},        // both windows expired
+	}
+	for i, st := range steps {
+		clk.t = clk.t.Add(st.advance)
+		if got := slidingAllow(ctx, cfg, "+258840000001"); got != st.want {
+			t.Fatalf("step %d: allow = %v, want %v", i+1, got, st.want)
+		}
+	}
+	if !slidingAllow(ctx, cfg, "+258840000002") {
+		t.Fatal("keys must be limited independently")
+	}
+}
+
+// plainStore is a core.Store without core.Counter.
+type plainStore struct{ core.Store }
+
+func TestStoreRateBackendIncr(t *testing.T) {
+	tests := []struct {
+		name string
+		st   core.Store
+	}{
+		{"counter", store.NewInMemoryStore(time.Minute, store.WithoutGC())},
+		{"get+put fallback", plainStore{store.NewInMemoryStore(time.Minute, store.WithoutGC())}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewStoreRateBackend(tt.st)
+			ctx := context.Background()
+			var wg sync.WaitGroup
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, err := b.Incr(ctx, "k", time.Minute); err != nil {
+						t.Error(err)
+					}
+				}()
+			}
+			wg.Wait()
+			if n, _ := b.Count(ctx, "k"); n != 50 {
+				t.Fatalf("Count = %d after 50 concurrent Incr, want 50", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/router"
+)
+
+// Timeout bounds a handler to d (or the step's own deadline, if sooner) and
+// returns fallback when it is exceeded, e.g. core.END("We'll SMS you the result.").
+//
+// The handler keeps running in the background on a detached context and a copy
+// of the session; its session writes and redirects are only applied if it
+// finishes in time. Use it for side effects that must complete regardless
+// (payments, provisioning) and notify the user out of band. A panic in the
+// handler ends the session with "Service unavailable.".
+//
+// The detached context (context.WithoutCancel) carries no deadline, so
+// c.Remaining() inside the handler reports no limit; budget with d instead.
+func Timeout(d time.Duration, fallback core.Reply) router.Middleware {
+	return func(next router.Handler) router.Handler {
+		return func(c *router.Ctx) core.Reply {
+			cc := *c
+			cc.Context = context.WithoutCancel(c.Context)
+			cc.Session = c.Session.Clone()
+
+			done := make(chan core.Reply, 1)
+			go func() {
+				defer func() {
+					if r := recover(); r != nil {
+						done <- core.END("Service unavailable.")
+					}
+				}()
+				done <- next(&cc)
+			}()
+
+			t := time.NewTimer(d)
+			defer t.Stop()
+			select {
+			case rep := <-done:
+				data := c.Session.Data()
+				for k := range data {
+					delete(data, k)
+				}
+				for k, v := range cc.Session.Data() {
+					data[k] = v
+				}
+				if n := cc.Next(); n != "" {
+					c.Redirect(n)
+				}
+				return rep
+			case <-t.C:
+				return fallback
+			case <-c.Done():
+				return fallback
+			}
+		}
+	}
+}
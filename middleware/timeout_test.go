@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/router"
+	"github.com/grahms/cardinal/store"
+)
+
+// timeoutEngine runs h on INPUT at /home under Timeout(d, CON "Please wait").
+func timeoutEngine(d time.Duration, h router.Handler) *core.Engine {
+	r := router.New("/home")
+	r.SHOW("/home", func(c *router.Ctx) core.Reply { return core.CON("Home k=" + c.Session.MustString("k")) })
+	r.INPUTWith("/home", h, Timeout(d, core.CON("Please wait")))
+	r.SHOW("/next", func(c *router.Ctx) core.Reply { return core.CON("Next k=" + c.Session.MustString("k")) })
+	return core.New(r.Mount(), core.Config{Store: store.NewInMemoryStore(time.Minute, store.WithoutGC())})
+}
+
+func step(t *testing.T, eng *core.Engine, text string) core.Reply {
+	t.Helper()
+	rep, err := eng.Handle(context.Background(), core.Request{SessionID: "s1", Msisdn: "1", Text: text})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rep
+}
+
+func TestTimeoutInTime(t *testing.T) {
+	eng := timeoutEngine(time.Second, func(c *router.Ctx) core.Reply {
+		c.Set("k", "v")
+		c.Redirect("/next")
+		return core.CON("")
+	})
+	step(t, eng, "")
+	if rep := step(t, eng, "1"); rep.Message != "Next k=v" {
+		t.Fatalf("got %q, want session write and redirect applied", rep.Message)
+	}
+}
+
+func TestTimeoutLate(t *testing.T) {
+	release, finished := make(chan struct{}), make(chan struct{})
+	eng := timeoutEngine(10*time.Millisecond, func(c *router.Ctx) core.Reply {
+		defer close(finished)
+		<-release
+		c.Set("k", "late")
+		c.Redirect("/next")
+		return core.CON("")
+	})
+	step(t, eng, "")
+	// the CON fallback keeps the user on /home, which is shown again
+	if rep := step(t, eng, "1"); rep.Message != "Home k=" {
+		t.Fatalf("got %q, want the fallback without the redirect", rep.Message)
+	}
+	close(release)
+	<-finished // the handler completes in the background
+	if rep := step(t, eng, ""); rep.Message != "Home k=" {
+		t.Fatalf("got %q, want late writes and redirect dropped", rep.Message)
+	}
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	eng := timeoutEngine(time.Second, func(c *router.Ctx) core.Reply { panic("boom") })
+	step(t, eng, "")
+	if rep := step(t, eng, "1"); rep.Continue || rep.Message != "Service unavailable." {
+		t.Fatalf("got %+v, want END Service unavailable.", rep)
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		mw      []router.Middleware
+		check   func(time.Duration) bool
+	}{
+		{"no deadline", 0, nil, func(d time.Duration) bool { return d == time.Duration(math.MaxInt64) }},
+		{"RequestTimeout", time.Minute, nil, func(d time.Duration) bool { return d > 0 && d <= time.Minute }},
+		{"under Timeout", time.Minute, []router.Middleware{Timeout(time.Second, core.END(""))},
+			func(d time.Duration) bool { return d == time.Duration(math.MaxInt64) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got time.Duration
+			r := router.New("/home")
+			r.SHOWWith("/home", func(c *router.Ctx) core.Reply {
+				got = c.Remaining()
+				return core.CON("Home")
+			}, tt.mw...)
+			eng := core.New(r.Mount(), core.Config{Store: store.NewInMemoryStore(time.Minute, store.WithoutGC()), RequestTimeout: tt.timeout})
+			step(t, eng, "")
+			if !tt.check(got) {
+				t.Fatalf("Remaining() = %v", got)
+			}
+		})
+	}
+}
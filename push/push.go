@@ -0,0 +1,104 @@
+// Package push starts network-initiated (push) USSD dialogues.
+//
+// A Pusher opens a session on a chosen router path, renders its first screen
+// through the normal engine and hands it to a Gateway. The user's answer
+// arrives through your regular transport with the same session ID and is
+// handled by the engine like any other step.
+//
+//	p := push.New(eng, gw)
+//	sid, err := p.Start(ctx, "+258840000001", "/approve/tx-42", map[string]any{"amount": 500})
+package push
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/router"
+)
+
+// Message is a network-initiated screen.
+type Message struct {
+	SessionID   string
+	Msisdn      string
+	ServiceCode string
+	Reply       core.Reply // CON: expects an answer; END: notification only
+}
+
+// Gateway delivers pushed screens to the network (USSR/USSN over SMPP,
+// an aggregator's push API, ...). It must propagate SessionID so the user's
+// answer comes back on the same session.
+type Gateway interface {
+	Push(ctx context.Context, m Message) error
+}
+
+// GatewayFunc adapts a function to Gateway.
+type GatewayFunc func(ctx context.Context, m Message) error
+
+func (f GatewayFunc) Push(ctx context.Context, m Message) error { return f(ctx, m) }
+
+// Starter opens seeded sessions and handles the steps that follow.
+// *core.Engine and *dispatch.Dispatcher implement it; with a Dispatcher, set
+// Pusher.ServiceCode so the push reaches the right service.
+type Starter interface {
+	core.Handler
+	Start(ctx context.Context, req core.Request, seed map[string]any) (core.Reply, error)
+}
+
+var _ Starter = (*core.Engine)(nil)
+
+// Pusher creates push sessions.
+type Pusher struct {
+	eng Starter
+	gw  Gateway
+	seq atomic.Uint64
+
+	// ServiceCode is sent with every push (optional).
+	ServiceCode string
+	// NewSessionID generates session IDs (default "push-<msisdn>-<unix-nano>-<n>").
+	NewSessionID func(msisdn string) string
+}
+
+func New(eng Starter, gw Gateway) *Pusher {
+	return &Pusher{eng: eng, gw: gw}
+}
+
+// Start opens a session for msisdn on path (with optional session data),
+// renders the first screen and pushes it. If the push fails the session is
+// aborted so it does not linger in the store.
+func (p *Pusher) Start(ctx context.Context, msisdn, path string, data map[string]any) (string, error) {
+	if msisdn == "" {
+		return "", errors.New("push: missing msisdn")
+	}
+	sid := p.sessionID(msisdn)
+	req := core.Request{
+		SessionID:   sid,
+		Msisdn:      msisdn,
+		ServiceCode: p.ServiceCode,
+		Meta:        map[string]string{"push": "true"},
+	}
+	rep, err := p.eng.Start(ctx, req, router.StartAt(path, data))
+	if err != nil {
+		return "", err
+	}
+	err = p.gw.Push(ctx, Message{SessionID: sid, Msisdn: msisdn, ServiceCode: p.ServiceCode, Reply: rep})
+	if err != nil {
+		if rep.Continue {
+			req.Kind = core.KindAbort
+			_, _ = p.eng.Handle(ctx, req)
+		}
+		return "", err
+	}
+	return sid, nil
+}
+
+func (p *Pusher) sessionID(msisdn string) string {
+	if p.NewSessionID != nil {
+		return p.NewSessionID(msisdn)
+	}
+	n := p.seq.Add(1)
+	return "push-" + msisdn + "-" + strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatUint(n, 10)
+}
@@ -0,0 +1,120 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/dispatch"
+	"github.com/grahms/cardinal/router"
+	"github.com/grahms/cardinal/store"
+)
+
+var _ Starter = (*dispatch.Dispatcher)(nil)
+
+// approveApp asks to approve the amount in the session; "1" approves.
+func approveApp() core.App {
+	r := router.New("/home")
+	r.SHOW("/home", func(c *router.Ctx) core.Reply { return core.END("Home") })
+	r.SHOW("/approve/:id", func(c *router.Ctx) core.Reply {
+		amount, _ := c.Get("amount")
+		return core.CON(c.Param("id") + " " + amount.(string) + "\n1) Approve")
+	})
+	r.INPUT("/approve/:id", func(c *router.Ctx) core.Reply {
+		if c.In() == "1" {
+			return core.END("Approved " + c.Param("id"))
+		}
+		return core.CON("")
+	})
+	return r.Mount()
+}
+
+type outbox struct {
+	sent []Message
+	err  error
+}
+
+func (o *outbox) Push(ctx context.Context, m Message) error {
+	o.sent = append(o.sent, m)
+	return o.err
+}
+
+func TestStart(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewInMemoryStore(time.Minute, store.WithoutGC())
+	eng := core.New(approveApp(), core.Config{Store: st})
+	gw := &outbox{}
+	p := New(eng, gw)
+	p.ServiceCode = "*144#"
+
+	sid, err := p.Start(ctx, "+258840000001", "/approve/tx-42", map[string]any{"amount": "500"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Message{SessionID: sid, Msisdn: "+258840000001", ServiceCode: "*144#", Reply: core.CON("tx-42 500\n1) Approve")}
+	if len(gw.sent) != 1 || gw.sent[0] != want {
+		t.Fatalf("pushed %+v, want %+v", gw.sent, want)
+	}
+	rep, err := eng.Handle(ctx, core.Request{SessionID: sid, Msisdn: "+258840000001", Text: "1"})
+	if err != nil || rep != core.END("Approved tx-42") {
+		t.Fatalf("answer: %+v, %v", rep, err)
+	}
+
+	if _, err := p.Start(ctx, "", "/approve/tx-42", nil); err == nil {
+		t.Fatal("missing msisdn accepted")
+	}
+}
+
+func TestStartPushFails(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewInMemoryStore(time.Minute, store.WithoutGC())
+	eng := core.New(approveApp(), core.Config{Store: st})
+	boom := errors.New("gateway down")
+	p := New(eng, &outbox{err: boom})
+	p.NewSessionID = func(string) string { return "push-1" }
+
+	sid, err := p.Start(ctx, "+258840000001", "/approve/tx-42", map[string]any{"amount": "500"})
+	if !errors.Is(err, boom) || sid != "" {
+		t.Fatalf("Start = %q, %v; want %v", sid, err, boom)
+	}
+	if data, _ := st.Get(ctx, "push-1"); len(data) != 0 {
+		t.Fatalf("session left in the store: %v", data)
+	}
+}
+
+func TestStartThroughDispatcher(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewInMemoryStore(time.Minute, store.WithoutGC())
+	d := dispatch.New(st,
+		dispatch.Service{Code: "*144#", App: approveApp()},
+		dispatch.Service{Code: "*555#", App: approveApp()},
+	)
+	gw := &outbox{}
+	p := New(d, gw)
+	p.ServiceCode = "*555#"
+
+	sid, err := p.Start(ctx, "+258840000001", "/approve/tx-7", map[string]any{"amount": "20"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gw.sent) != 1 || gw.sent[0].Reply != core.CON("tx-7 20\n1) Approve") {
+		t.Fatalf("pushed %+v", gw.sent)
+	}
+	// The answer arrives without a service code and must find *555#.
+	rep, err := d.Handle(ctx, core.Request{SessionID: sid, Msisdn: "+258840000001", Text: "1"})
+	if err != nil || rep != core.END("Approved tx-7") {
+		t.Fatalf("answer: %+v, %v", rep, err)
+	}
+
+	gw.err = errors.New("gateway down")
+	p.NewSessionID = func(string) string { return "push-2" }
+	if _, err := p.Start(ctx, "+258840000001", "/approve/tx-8", map[string]any{"amount": "20"}); err == nil {
+		t.Fatal("push failure not reported")
+	}
+	rep, _ = d.Handle(ctx, core.Request{SessionID: "push-2", Msisdn: "+258840000001", Text: "1"})
+	if rep.Message == "Approved tx-8" {
+		t.Fatal("aborted push session still answers")
+	}
+}
@@ -0,0 +1,343 @@
+// Package record captures production dialogues so they can be replayed as
+// regression tests (see testkit.ReplayFile).
+//
+// A Recorder wraps any core.Handler (an engine, a dispatcher) and writes one
+// Recording per session to a Sink once the session ends. MSISDNs are
+// pseudonymized and inputs typed on PIN/password screens are redacted before
+// anything reaches the sink. Screens are kept verbatim; see Config.Sanitize.
+//
+//	sink, _ := record.NewFileSink("/var/log/ussd/sessions.jsonl")
+//	rec := record.New(eng, record.Config{Sink: sink})
+//	mux.Handle("/ussd", transport.AfricaTalkingHandler(rec))
+package record
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+)
+
+// Redacted replaces secret inputs in recordings. It must not contain "*",
+// which separates accumulated input.
+const Redacted = "[redacted]"
+
+// Recording is one session as the engine saw it.
+type Recording struct {
+	SessionID   string     `json:"sessionId"`
+	Msisdn      string     `json:"msisdn"`
+	ServiceCode string     `json:"serviceCode,omitempty"`
+	Started     time.Time  `json:"started"`
+	Steps       []Exchange `json:"steps"`
+}
+
+// Exchange is one request and the reply it got.
+type Exchange struct {
+	Text     string `json:"text"` // as received, e.g. "1*100" from accumulating gateways
+	Kind     string `json:"kind,omitempty"`
+	Continue bool   `json:"continue"`
+	Message  string `json:"message"`
+	Error    string `json:"error,omitempty"`
+	Millis   int64  `json:"ms"`
+}
+
+// Sink stores finished recordings. Implementations must be safe for concurrent use.
+type Sink interface {
+	Write(rec Recording) error
+}
+
+// SinkFunc adapts a function to Sink.
+type SinkFunc func(rec Recording) error
+
+func (f SinkFunc) Write(rec Recording) error { return f(rec) }
+
+// Config configures a Recorder.
+type Config struct {
+	Sink Sink
+
+	// Filter limits recording to matching requests (e.g. one subscriber who
+	// reported a bug). Decided on a session's first request. Default: all.
+	Filter func(req core.Request) bool
+
+	// Secret matches screens whose next input must be redacted.
+	// Default: (?i)\b(pin|password|senha|otp)\b
+	Secret *regexp.Regexp
+
+	// KeepMSISDN records numbers as-is instead of pseudonymizing them.
+	KeepMSISDN bool
+
+	// Sanitize, if set, runs last on every recording before it is written.
+	//
+	// Screens are recorded verbatim: balances, recipient numbers and names
+	// shown to the subscriber reach the sink unless Sanitize removes them.
+	// MaskNumbers pseudonymizes MSISDN-shaped digits in screens.
+	Sanitize func(rec *Recording)
+
+	// Idle flushes sessions that saw no request for this long, for gateways
+	// that never send a final request (default 5m).
+	Idle time.Duration
+
+	// OnError reports sink failures (default: ignored).
+	OnError func(err error)
+}
+
+// Recorder is a core.Handler that records what it forwards to next.
+type Recorder struct {
+	next core.Handler
+	cfg  Config
+
+	mu        sync.Mutex
+	open      map[string]*session
+	nextSweep time.Time
+}
+
+type session struct {
+	rec      Recording
+	inputs   int   // non-empty inputs so far
+	secretAt []int // 1-based positions of inputs typed on secret screens
+	skip     bool
+	secret   bool // last screen asked for a secret
+	seen     time.Time
+}
+
+var _ core.Handler = (*Recorder)(nil)
+
+var defaultSecret = regexp.MustCompile(`(?i)\b(pin|password|senha|otp)\b`)
+
+func New(next core.Handler, cfg Config) *Recorder {
+	if cfg.Secret == nil {
+		cfg.Secret = defaultSecret
+	}
+	if cfg.Idle <= 0 {
+		cfg.Idle = 5 * time.Minute
+	}
+	return &Recorder{next: next, cfg: cfg, open: map[string]*session{}}
+}
+
+// Handle forwards req and records the exchange.
+func (r *Recorder) Handle(ctx context.Context, req core.Request) (core.Reply, error) {
+	t0 := time.Now()
+	rep, err := r.next.Handle(ctx, req)
+	d := time.Since(t0)
+
+	var done []*session
+	r.mu.Lock()
+	s := r.open[req.SessionID]
+	if s != nil && req.Kind == core.KindBegin {
+		done = append(done, s) // gateway reused the session ID
+		s = nil
+	}
+	if s == nil {
+		s = &session{
+			rec: Recording{
+				SessionID:   req.SessionID,
+				Msisdn:      req.Msisdn,
+				ServiceCode: req.ServiceCode,
+				Started:     t0,
+			},
+			skip: r.cfg.Filter != nil && !r.cfg.Filter(req),
+		}
+		r.open[req.SessionID] = s
+	}
+	s.seen = t0
+	text := req.Text
+	if lastToken(req.Text) != "" && req.Kind != core.KindAbort && req.Kind != core.KindTimeout {
+		s.inputs++
+		if s.secret {
+			s.secretAt = append(s.secretAt, s.inputs)
+		}
+		text = s.redact(req.Text)
+	}
+	ex := Exchange{Text: text, Continue: rep.Continue, Message: rep.Message, Millis: d.Milliseconds()}
+	if req.Kind != core.KindUnspecified {
+		ex.Kind = req.Kind.String()
+	}
+	if err != nil {
+		ex.Error = err.Error()
+	}
+	s.rec.Steps = append(s.rec.Steps, ex)
+	s.secret = rep.Continue && r.cfg.Secret.MatchString(rep.Message)
+
+	if err != nil || !rep.Continue {
+		delete(r.open, req.SessionID)
+		done = append(done, s)
+	}
+	done = append(done, r.sweep(t0)...)
+	r.mu.Unlock()
+
+	for _, s := range done {
+		r.write(s)
+	}
+	return rep, err
+}
+
+// Flush writes every open session, e.g. on shutdown.
+func (r *Recorder) Flush() {
+	r.mu.Lock()
+	done := make([]*session, 0, len(r.open))
+	for k, s := range r.open {
+		delete(r.open, k)
+		done = append(done, s)
+	}
+	r.mu.Unlock()
+	for _, s := range done {
+		r.write(s)
+	}
+}
+
+// caller holds r.mu
+func (r *Recorder) sweep(now time.Time) []*session {
+	if now.Before(r.nextSweep) {
+		return nil
+	}
+	r.nextSweep = now.Add(time.Minute)
+	var out []*session
+	for k, s := range r.open {
+		if now.Sub(s.seen) > r.cfg.Idle {
+			delete(r.open, k)
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (r *Recorder) write(s *session) {
+	if s.skip || r.cfg.Sink == nil {
+		return
+	}
+	rec := s.rec
+	if !r.cfg.KeepMSISDN {
+		rec.Msisdn = MaskMSISDN(rec.Msisdn)
+	}
+	if r.cfg.Sanitize != nil {
+		r.cfg.Sanitize(&rec)
+	}
+	if err := r.cfg.Sink.Write(rec); err != nil && r.cfg.OnError != nil {
+		r.cfg.OnError(err)
+	}
+}
+
+// redact replaces the tokens of text that hold secret inputs. Secrets are
+// found by position, not value, so an amount that happens to equal the PIN is
+// kept. Accumulating gateways ("1*1234*2") repeat every input in order, ending
+// with the latest; other gateways send only the latest input.
+func (s *session) redact(text string) string {
+	if len(s.secretAt) == 0 {
+		return text
+	}
+	toks := strings.Split(text, "*")
+	accumulated := len(toks) >= s.inputs
+	for _, at := range s.secretAt {
+		i := len(toks) - 1 - (s.inputs - at)
+		if !accumulated && at != s.inputs {
+			continue
+		}
+		if i >= 0 {
+			toks[i] = Redacted
+		}
+	}
+	return strings.Join(toks, "*")
+}
+
+func lastToken(t string) string {
+	t = strings.TrimSpace(t)
+	if i := strings.LastIndexByte(t, '*'); i >= 0 {
+		return t[i+1:]
+	}
+	return t
+}
+
+// MaskMSISDN keeps the first 6 characters (country and network prefix) and
+// replaces the rest with digits derived from a hash, so one subscriber maps to
+// the same pseudonym across recordings while the number keeps its shape.
+func MaskMSISDN(msisdn string) string {
+	if len(msisdn) <= 6 {
+		return msisdn
+	}
+	h := fnv.New64a()
+	h.Write([]byte(msisdn))
+	sum := fmt.Sprintf("%020d", h.Sum64())
+	rest := []byte(msisdn[6:])
+	for i := range rest {
+		if rest[i] >= '0' && rest[i] <= '9' {
+			rest[i] = sum[len(sum)-1-i%len(sum)]
+		}
+	}
+	return msisdn[:6] + string(rest)
+}
+
+// msisdnLike matches international numbers in screens: an optional "+" and
+// 9 to 15 digits.
+var msisdnLike = regexp.MustCompile(`\+?\b\d{9,15}\b`)
+
+// MaskNumbers is a Config.Sanitize that runs MaskMSISDN over MSISDN-shaped
+// numbers in every screen, so recipients shown on confirmation screens are
+// pseudonymized like the caller.
+func MaskNumbers(rec *Recording) {
+	for i := range rec.Steps {
+		rec.Steps[i].Message = msisdnLike.ReplaceAllStringFunc(rec.Steps[i].Message, MaskMSISDN)
+	}
+}
+
+/* ---------- file sink ---------- */
+
+// FileSink appends recordings to a JSON Lines file.
+type FileSink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+var _ Sink = (*FileSink)(nil)
+
+// NewFileSink opens (or creates) path for appending.
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{f: f}, nil
+}
+
+func (s *FileSink) Write(rec Recording) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.f.Write(append(b, '\n'))
+	return err
+}
+
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
+
+// ReadFile loads the recordings in a JSON Lines file written by FileSink.
+func ReadFile(path string) ([]Recording, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var out []Recording
+	for i, line := range strings.Split(string(b), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var rec Recording
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return nil, fmt.Errorf("record: %s:%d: %w", path, i+1, err)
+		}
+		out = append(out, rec)
+	}
+	return out, nil
+}
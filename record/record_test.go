@@ -0,0 +1,137 @@
+package record
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/router"
+	"github.com/grahms/cardinal/store"
+)
+
+// transferEngine asks for a PIN, then an amount, and confirms.
+func transferEngine() *core.Engine {
+	r := router.New("/home")
+	r.SHOW("/home", func(c *router.Ctx) core.Reply { return core.CON("Welcome\n1) Send") })
+	r.INPUT("/home", func(c *router.Ctx) core.Reply { c.Redirect("/pin"); return core.CON("") })
+	r.SHOW("/pin", func(c *router.Ctx) core.Reply { return core.CON("Enter PIN") })
+	r.INPUT("/pin", func(c *router.Ctx) core.Reply { c.Redirect("/amount"); return core.CON("") })
+	r.SHOW("/amount", func(c *router.Ctx) core.Reply { return core.CON("Amount") })
+	r.INPUT("/amount", func(c *router.Ctx) core.Reply {
+		return core.END("Sent " + c.In() + " to +258841234567")
+	})
+	return core.New(r.Mount(), core.Config{Store: store.NewInMemoryStore(time.Minute, store.WithoutGC())})
+}
+
+func record(t *testing.T, cfg Config, texts ...string) Recording {
+	t.Helper()
+	var got []Recording
+	cfg.Sink = SinkFunc(func(rec Recording) error { got = append(got, rec); return nil })
+	rec := New(transferEngine(), cfg)
+	for _, text := range texts {
+		if _, err := rec.Handle(context.Background(), core.Request{SessionID: "s1", Msisdn: "+258840000001", Text: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rec.Flush()
+	if len(got) != 1 {
+		t.Fatalf("%d recordings, want 1", len(got))
+	}
+	return got[0]
+}
+
+func texts(rec Recording) []string {
+	var out []string
+	for _, ex := range rec.Steps {
+		out = append(out, ex.Text)
+	}
+	return out
+}
+
+func TestRedactByPosition(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []string
+		want []string
+	}{
+		{"last token", []string{"", "1", "1234", "1234"}, []string{"", "1", Redacted, "1234"}},
+		{"accumulated", []string{"", "1", "1*1234", "1*1234*1234"}, []string{"", "1", "1*" + Redacted, "1*" + Redacted + "*1234"}},
+		{"secret equals earlier choice", []string{"", "1", "1"}, []string{"", "1", Redacted}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := record(t, Config{}, tt.in...)
+			if got := texts(rec); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("texts = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecorderMasking(t *testing.T) {
+	rec := record(t, Config{}, "", "1", "1234", "50")
+	if rec.Msisdn == "+258840000001" || !strings.HasPrefix(rec.Msisdn, "+25884") || len(rec.Msisdn) != len("+258840000001") {
+		t.Fatalf("Msisdn = %q, want a pseudonym of the same shape", rec.Msisdn)
+	}
+	if rec.Msisdn != MaskMSISDN("+258840000001") {
+		t.Fatal("pseudonym is not stable")
+	}
+	if last := rec.Steps[len(rec.Steps)-1].Message; !strings.Contains(last, "+258841234567") {
+		t.Fatalf("screen = %q, want it verbatim without Sanitize", last)
+	}
+
+	rec = record(t, Config{KeepMSISDN: true, Sanitize: MaskNumbers}, "", "1", "1234", "50")
+	if rec.Msisdn != "+258840000001" {
+		t.Fatalf("Msisdn = %q with KeepMSISDN", rec.Msisdn)
+	}
+	last := rec.Steps[len(rec.Steps)-1].Message
+	if want := "Sent 50 to " + MaskMSISDN("+258841234567"); last != want {
+		t.Fatalf("screen = %q, want %q", last, want)
+	}
+}
+
+func TestRecorderFilter(t *testing.T) {
+	var n int
+	rec := New(transferEngine(), Config{
+		Sink:   SinkFunc(func(Recording) error { n++; return nil }),
+		Filter: func(req core.Request) bool { return req.Msisdn == "+258840000002" },
+	})
+	ctx := context.Background()
+	_, _ = rec.Handle(ctx, core.Request{SessionID: "a", Msisdn: "+258840000001"})
+	_, _ = rec.Handle(ctx, core.Request{SessionID: "b", Msisdn: "+258840000002"})
+	rec.Flush()
+	if n != 1 {
+		t.Fatalf("%d recordings written, want 1", n)
+	}
+}
+
+func TestFileSinkRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.jsonl")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Recording{
+		{SessionID: "a", Msisdn: "+258840000001", Started: time.Unix(0, 0).UTC(), Steps: []Exchange{{Text: "", Continue: true, Message: "Welcome"}}},
+		{SessionID: "b", Msisdn: "+258840000002", ServiceCode: "*144#", Started: time.Unix(60, 0).UTC(), Steps: []Exchange{{Text: "1", Kind: "begin", Message: "Bye"}}},
+	}
+	for _, rec := range want {
+		if err := sink.Write(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,59 @@
+package router
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/store"
+)
+
+func TestOnAbortSkipsMiddleware(t *testing.T) {
+	r := New("/home")
+	maintenance := false
+	r.Use(func(next Handler) Handler { // short-circuits like middleware.Maintenance
+		return func(c *Ctx) core.Reply {
+			if maintenance {
+				return core.END("Closed for maintenance.")
+			}
+			return next(c)
+		}
+	})
+	r.SHOW("/home", func(c *Ctx) core.Reply { return core.CON("Welcome") })
+	var cleaned string
+	r.OnAbort(func(c *Ctx) core.Reply {
+		cleaned = c.Path()
+		return core.END("")
+	})
+	eng := core.New(r.Mount(), core.Config{Store: store.NewInMemoryStore(time.Minute, store.WithoutGC())})
+
+	ctx := context.Background()
+	if _, err := eng.Handle(ctx, core.Request{SessionID: "s", Msisdn: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	maintenance = true
+	if _, err := eng.Handle(ctx, core.Request{SessionID: "s", Msisdn: "1", Kind: core.KindAbort}); err != nil {
+		t.Fatal(err)
+	}
+	if cleaned != "/home" {
+		t.Fatalf("abort hook saw path %q, want /home", cleaned)
+	}
+}
+
+func TestOnAbortRecoversPanic(t *testing.T) {
+	r := New("/home")
+	r.SHOW("/home", func(c *Ctx) core.Reply { return core.CON("Welcome") })
+	r.OnAbort(func(c *Ctx) core.Reply { panic("backend down") })
+	st := store.NewInMemoryStore(time.Minute, store.WithoutGC())
+	eng := core.New(r.Mount(), core.Config{Store: st})
+
+	ctx := context.Background()
+	_, _ = eng.Handle(ctx, core.Request{SessionID: "s", Msisdn: "1"})
+	if _, err := eng.Handle(ctx, core.Request{SessionID: "s", Msisdn: "1", Kind: core.KindTimeout}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := st.Get(ctx, "s"); len(data) != 0 {
+		t.Fatal("session not deleted after a panicking abort hook")
+	}
+}
@@ -0,0 +1,141 @@
+package router
+
+import (
+	"sort"
+	"sync"
+)
+
+// Coverage records which SHOW/INPUT handlers and which menu options ran.
+// Attach it with Router.Cover; one Coverage can collect from many routers
+// (e.g. one per test), merging by route pattern.
+type Coverage struct {
+	mu     sync.Mutex
+	routes map[string]*RouteCoverage
+}
+
+// RouteCoverage is the hit count for one route pattern.
+type RouteCoverage struct {
+	Pattern  string
+	HasShow  bool
+	HasInput bool
+	Shows    int
+	Inputs   int
+	Options  []OptionCoverage // in the order the menu offers them
+}
+
+// OptionCoverage counts how often a menu option was chosen.
+type OptionCoverage struct {
+	Label string
+	Hits  int
+}
+
+func NewCoverage() *Coverage {
+	return &Coverage{routes: map[string]*RouteCoverage{}}
+}
+
+// Cover starts recording rt into cv until the returned func is called.
+// All registered routes are declared up front so unexecuted ones show up.
+func (rt *Router) Cover(cv *Coverage) (stop func()) {
+	cv.mu.Lock()
+	declare := func(r route) {
+		rc := cv.route(r.pattern)
+		rc.HasShow = rc.HasShow || r.show != nil
+		rc.HasInput = rc.HasInput || r.input != nil
+	}
+	for _, r := range rt.exact {
+		declare(r)
+	}
+	for _, r := range rt.param {
+		declare(r)
+	}
+	cv.mu.Unlock()
+
+	untrace := rt.Trace(func(pattern string, show bool) {
+		cv.mu.Lock()
+		defer cv.mu.Unlock()
+		rc := cv.route(pattern)
+		if show {
+			rc.Shows++
+		} else {
+			rc.Inputs++
+		}
+	})
+	rt.traceMu.Lock()
+	rt.covers = append(rt.covers, cv)
+	rt.traceMu.Unlock()
+
+	return func() {
+		untrace()
+		rt.traceMu.Lock()
+		defer rt.traceMu.Unlock()
+		for i, x := range rt.covers {
+			if x == cv {
+				rt.covers = append(rt.covers[:i:i], rt.covers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Routes returns a snapshot sorted by pattern.
+func (cv *Coverage) Routes() []RouteCoverage {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	out := make([]RouteCoverage, 0, len(cv.routes))
+	for _, rc := range cv.routes {
+		cp := *rc
+		cp.Options = append([]OptionCoverage(nil), rc.Options...)
+		out = append(out, cp)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Pattern < out[j].Pattern })
+	return out
+}
+
+// caller holds cv.mu
+func (cv *Coverage) route(pattern string) *RouteCoverage {
+	rc, ok := cv.routes[pattern]
+	if !ok {
+		rc = &RouteCoverage{Pattern: pattern}
+		cv.routes[pattern] = rc
+	}
+	return rc
+}
+
+// caller holds cv.mu
+func (rc *RouteCoverage) option(label string) *OptionCoverage {
+	for i := range rc.Options {
+		if rc.Options[i].Label == label {
+			return &rc.Options[i]
+		}
+	}
+	rc.Options = append(rc.Options, OptionCoverage{Label: label})
+	return &rc.Options[len(rc.Options)-1]
+}
+
+// OfferOptions tells attached coverage collectors which options the current
+// screen lists, so options never chosen are reported. menu.Builder calls it.
+func (c *Ctx) OfferOptions(labels ...string) {
+	c.eachCoverage(func(rc *RouteCoverage) {
+		for _, l := range labels {
+			rc.option(l)
+		}
+	})
+}
+
+// HitOption records that the user chose option label on the current screen.
+func (c *Ctx) HitOption(label string) {
+	c.eachCoverage(func(rc *RouteCoverage) { rc.option(label).Hits++ })
+}
+
+func (c *Ctx) eachCoverage(fn func(rc *RouteCoverage)) {
+	if c == nil || c.rt == nil || c.pattern == "" {
+		return
+	}
+	c.rt.traceMu.RLock()
+	defer c.rt.traceMu.RUnlock()
+	for _, cv := range c.rt.covers {
+		cv.mu.Lock()
+		fn(cv.route(c.pattern))
+		cv.mu.Unlock()
+	}
+}
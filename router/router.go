@@ -2,7 +2,11 @@ package router
 
 import (
 	"context"
+	"math"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/grahms/cardinal/core"
 )
@@ -15,6 +19,9 @@ type Ctx struct {
 	in      string
 	next    string
 	params  map[string]string
+	flags   FlagSource
+	rt      *Router
+	pattern string
 }
 
 func (c *Ctx) Path() string             { return c.path }
@@ -25,6 +32,25 @@ func (c *Ctx) Set(k string, v any)      { c.Session.Set(k, v) }
 func (c *Ctx) Get(k string) (any, bool) { return c.Session.Get(k) }
 func (c *Ctx) Param(k string) string    { return c.params[k] }
 
+// Flag reports whether feature flag name is on for the caller's MSISDN.
+// It is always false unless the router has a FlagSource (see UseFlags).
+func (c *Ctx) Flag(name string) bool {
+	return c.flags != nil && c.flags.Enabled(name, c.Req.Msisdn)
+}
+
+// Remaining reports how much of the step's deadline budget is left.
+// It returns 0 once the deadline has passed and math.MaxInt64 if there is none.
+func (c *Ctx) Remaining() time.Duration {
+	dl, ok := c.Deadline()
+	if !ok {
+		return time.Duration(math.MaxInt64)
+	}
+	if d := time.Until(dl); d > 0 {
+		return d
+	}
+	return 0
+}
+
 type Handler func(*Ctx) core.Reply
 
 type Middleware func(Handler) Handler
@@ -35,20 +61,47 @@ type route struct {
 	input   Handler
 }
 
+// FlagSource answers feature-flag lookups for Ctx.Flag (e.g. *flags.Registry).
+type FlagSource interface {
+	Enabled(name, msisdn string) bool
+}
+
 type Router struct {
 	start string
 	exact map[string]route
 	param []route
 
-	mws []Middleware
+	mws     []Middleware
+	flags   FlagSource
+	onAbort Handler
+
+	traceMu sync.RWMutex
+	tracers []*Tracer
+	covers  []*Coverage
 }
 
+// Tracer is told which route pattern ran each time a SHOW (show == true) or
+// INPUT handler is executed. Test tools use it to find unreached screens.
+type Tracer func(pattern string, show bool)
+
 func New(start string) *Router {
 	return &Router{start: start, exact: map[string]route{}, param: []route{}}
 }
 
 func (rt *Router) Use(mw ...Middleware) { rt.mws = append(rt.mws, mw...) }
 
+// OnAbort registers a cleanup handler for sessions the gateway aborted or timed
+// out (see core.KindAbort). c.Path() is the screen the user was on; the reply
+// is discarded.
+//
+// Middleware registered with Use does NOT apply: ACL, maintenance or rate-limit
+// middleware answers with its own reply without calling next, which would skip
+// the cleanup. Panics in h are recovered. Wrap h yourself for logging or metrics.
+func (rt *Router) OnAbort(h Handler) { rt.onAbort = h }
+
+// UseFlags makes f available to handlers through Ctx.Flag.
+func (rt *Router) UseFlags(f FlagSource) { rt.flags = f }
+
 func (rt *Router) SHOW(path string, h Handler)  { rt.add(path, h, true) }
 func (rt *Router) INPUT(path string, h Handler) { rt.add(path, h, false) }
 
@@ -57,14 +110,114 @@ func (rt *Router) add(path string, h Handler, isShow bool) {
 	rt.addCore(path, h, isShow)
 }
 
-func (rt *Router) Mount() core.App { return &app{rt: rt} }
+func (rt *Router) Mount() core.App { return &app{rt: rt, start: rt.start} }
 
-type app struct{ rt *Router }
+// MountAt mounts the router with a different start path, so several service
+// codes can enter the same route tree at different screens.
+func (rt *Router) MountAt(start string) core.App { return &app{rt: rt, start: start} }
+
+// Trace registers fn for every handler execution until the returned func is called.
+func (rt *Router) Trace(fn Tracer) (untrace func()) {
+	p := &fn
+	rt.traceMu.Lock()
+	rt.tracers = append(rt.tracers, p)
+	rt.traceMu.Unlock()
+	return func() {
+		rt.traceMu.Lock()
+		defer rt.traceMu.Unlock()
+		for i, x := range rt.tracers {
+			if x == p {
+				rt.tracers = append(rt.tracers[:i:i], rt.tracers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (rt *Router) trace(pattern string, show bool) {
+	rt.traceMu.RLock()
+	defer rt.traceMu.RUnlock()
+	for _, fn := range rt.tracers {
+		(*fn)(pattern, show)
+	}
+}
+
+// Routes returns every registered route pattern, sorted.
+func (rt *Router) Routes() []string {
+	out := make([]string, 0, len(rt.exact)+len(rt.param))
+	for p := range rt.exact {
+		out = append(out, p)
+	}
+	for _, r := range rt.param {
+		out = append(out, r.pattern)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Pattern returns the registered pattern that path resolves to.
+func (rt *Router) Pattern(path string) (string, bool) {
+	if _, ok := rt.exact[path]; ok {
+		return path, true
+	}
+	for _, r := range rt.param {
+		if _, ok := matchParams(path, r.pattern); ok {
+			return r.pattern, true
+		}
+	}
+	return "", false
+}
+
+// CurrentPath returns the screen a session is on, from its stored data.
+func CurrentPath(data map[string]any) string {
+	p, _ := data["_p"].(string)
+	return p
+}
+
+// Params resolves path against the registered routes and returns its
+// parameters (empty for exact routes). ok is false if no route matches.
+func (rt *Router) Params(path string) (params map[string]string, ok bool) {
+	if _, ok := rt.exact[path]; ok {
+		return map[string]string{}, true
+	}
+	for _, r := range rt.param {
+		if params, ok := matchParams(path, r.pattern); ok {
+			return params, true
+		}
+	}
+	return nil, false
+}
+
+// StartAt returns session seed data that makes a new session open on path
+// instead of the router's start path (see core.Engine.Start). Extra keys in
+// data are copied into the session as-is.
+func StartAt(path string, data map[string]any) map[string]any {
+	seed := make(map[string]any, len(data)+1)
+	for k, v := range data {
+		seed[k] = v
+	}
+	seed["_p"] = path
+	return seed
+}
+
+type app struct {
+	rt    *Router
+	start string
+}
+
+func (a *app) Abort(ctx context.Context, s *core.Session, req core.Request) {
+	if a.rt.onAbort == nil {
+		return
+	}
+	cc := &Ctx{Context: ctx, Session: s, Req: req, path: mustString(s, "_p"), flags: a.rt.flags, rt: a.rt}
+	defer func() { _ = recover() }() // the session is deleted either way
+	_ = a.rt.onAbort(cc)
+}
 
 func (a *app) Handle(ctx context.Context, s *core.Session, req core.Request) (core.Reply, error) {
 	path := mustString(s, "_p")
 	if path == "" {
-		path = a.rt.start
+		path = a.start
 		s.Set("_p", path)
 		return a.execSHOW(ctx, s, req, path), nil
 	}
@@ -134,19 +287,21 @@ func (rt *Router) addCore(path string, h Handler, isShow bool) {
 }
 
 func (a *app) execSHOW(ctx context.Context, s *core.Session, req core.Request, path string) core.Reply {
-	h, params := a.match(path, true)
+	h, params, pattern := a.match(path, true)
 	if h == nil {
 		return core.END("Service unavailable.")
 	}
-	cc := &Ctx{Context: ctx, Session: s, Req: req, path: path, params: params}
+	a.rt.trace(pattern, true)
+	cc := &Ctx{Context: ctx, Session: s, Req: req, path: path, params: params, flags: a.rt.flags, rt: a.rt, pattern: pattern}
 	return h(cc)
 }
 func (a *app) execINPUT(ctx context.Context, s *core.Session, req core.Request, path, in string) core.Reply {
-	h, params := a.match(path, false)
+	h, params, pattern := a.match(path, false)
 	if h == nil {
 		return core.END("Service unavailable.")
 	}
-	cc := &Ctx{Context: ctx, Session: s, Req: req, path: path, in: in, params: params}
+	a.rt.trace(pattern, false)
+	cc := &Ctx{Context: ctx, Session: s, Req: req, path: path, in: in, params: params, flags: a.rt.flags, rt: a.rt, pattern: pattern}
 	reply := h(cc)
 	if cc.next != "" {
 		s.Set("_next", cc.next)
@@ -154,22 +309,22 @@ func (a *app) execINPUT(ctx context.Context, s *core.Session, req core.Request,
 	return reply
 }
 
-func (a *app) match(path string, wantSHOW bool) (Handler, map[string]string) {
+func (a *app) match(path string, wantSHOW bool) (Handler, map[string]string, string) {
 	if r, ok := a.rt.exact[path]; ok {
 		if wantSHOW {
-			return r.show, nil
+			return r.show, nil, r.pattern
 		}
-		return r.input, nil
+		return r.input, nil, r.pattern
 	}
 	for _, r := range a.rt.param {
 		if params, ok := matchParams(path, r.pattern); ok {
 			if wantSHOW {
-				return r.show, params
+				return r.show, params, r.pattern
 			}
-			return r.input, params
+			return r.input, params, r.pattern
 		}
 	}
-	return nil, nil
+	return nil, nil, ""
 }
 
 func wrap(h Handler, mws []Middleware) Handler {
@@ -12,6 +12,11 @@ type InMemory struct {
 	mu     sync.Mutex
 	data   map[string]item
 	defTTL time.Duration
+
+	clock core.Clock // nil: wall clock
+	noGC  bool
+	stop  chan struct{}
+	once  sync.Once
 }
 
 type item struct {
@@ -19,29 +24,66 @@ type item struct {
 	exp time.Time
 }
 
-func NewInMemoryStore(defaultTTL time.Duration) *InMemory {
-	m := &InMemory{data: make(map[string]item), defTTL: defaultTTL}
-	go m.gc()
+// InMemoryOption configures NewInMemoryStore.
+type InMemoryOption func(*InMemory)
+
+// WithClock makes the store read time from c, including in its background GC.
+// A clock carried by the request context (core.WithClock) still wins for
+// Get/Put. Give tests the fake clock they advance, so the GC and session
+// expiry agree:
+//
+//	clk := testkit.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+//	st := store.NewInMemoryStore(time.Minute, store.WithClock(clk))
+func WithClock(c core.Clock) InMemoryOption {
+	return func(m *InMemory) { m.clock = c }
+}
+
+// WithoutGC disables the background sweep goroutine; expired sessions are
+// still invisible to Get, and Sweep removes them on demand. Useful for
+// short-lived stores (fuzzing, per-test engines).
+func WithoutGC() InMemoryOption {
+	return func(m *InMemory) { m.noGC = true }
+}
+
+func NewInMemoryStore(defaultTTL time.Duration, opts ...InMemoryOption) *InMemory {
+	m := &InMemory{data: make(map[string]item), defTTL: defaultTTL, stop: make(chan struct{})}
+	for _, o := range opts {
+		o(m)
+	}
+	if !m.noGC {
+		go m.gc()
+	}
 	return m
 }
 
-func (m *InMemory) Get(_ context.Context, sid string) (map[string]any, error) {
+// now prefers a clock carried by ctx, then the store's clock.
+func (m *InMemory) now(ctx context.Context) time.Time {
+	c := core.ClockFrom(ctx)
+	if _, wall := c.(core.SystemClock); wall && m.clock != nil {
+		return m.clock.Now()
+	}
+	return c.Now()
+}
+
+func (m *InMemory) Get(ctx context.Context, sid string) (map[string]any, error) {
+	now := m.now(ctx)
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	it, ok := m.data[sid]
-	if !ok || time.Now().After(it.exp) {
+	if !ok || now.After(it.exp) {
 		return map[string]any{}, nil
 	}
 	return clone(it.val), nil
 }
 
-func (m *InMemory) Put(_ context.Context, sid string, d map[string]any, ttl time.Duration) error {
+func (m *InMemory) Put(ctx context.Context, sid string, d map[string]any, ttl time.Duration) error {
+	now := m.now(ctx)
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if ttl <= 0 {
 		ttl = m.defTTL
 	}
-	m.data[sid] = item{val: clone(d), exp: time.Now().Add(ttl)}
+	m.data[sid] = item{val: clone(d), exp: now.Add(ttl)}
 	return nil
 }
 
@@ -54,18 +96,75 @@ func (m *InMemory) Del(_ context.Context, sid string) error {
 
 func (m *InMemory) gc() {
 	t := time.NewTicker(time.Minute)
-	for range t.C {
-		now := time.Now()
-		m.mu.Lock()
-		for k, it := range m.data {
-			if now.After(it.exp) {
-				delete(m.data, k)
-			}
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			m.Sweep(m.now(context.Background()))
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// Incr atomically adds one to the counter at key (core.Counter). Counters
+// share the session keyspace and expire like sessions.
+func (m *InMemory) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	now := m.now(ctx)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	it, ok := m.data[key]
+	if !ok || now.After(it.exp) {
+		if ttl <= 0 {
+			ttl = m.defTTL
+		}
+		it = item{val: map[string]any{}, exp: now.Add(ttl)}
+	}
+	n, _ := it.val["n"].(int64)
+	n++
+	it.val = map[string]any{"n": n}
+	m.data[key] = it
+	return n, nil
+}
+
+// Count returns the counter at key (core.Counter).
+func (m *InMemory) Count(ctx context.Context, key string) (int64, error) {
+	now := m.now(ctx)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	it, ok := m.data[key]
+	if !ok || now.After(it.exp) {
+		return 0, nil
+	}
+	n, _ := it.val["n"].(int64)
+	return n, nil
+}
+
+// Close stops the background GC. The store stays usable.
+func (m *InMemory) Close() error {
+	m.once.Do(func() { close(m.stop) })
+	return nil
+}
+
+// Sweep drops every session expired at now. The background GC calls it every
+// minute with the store's clock; tests can call it directly.
+func (m *InMemory) Sweep(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k, it := range m.data {
+		if now.After(it.exp) {
+			delete(m.data, k)
 		}
-		m.mu.Unlock()
 	}
 }
 
+// Len reports how many sessions are held, including expired ones not yet swept.
+func (m *InMemory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.data)
+}
+
 func clone(m0 map[string]any) map[string]any {
 	if m0 == nil {
 		return map[string]any{}
@@ -78,4 +177,7 @@ func clone(m0 map[string]any) map[string]any {
 }
 
 // Ensure interface conformance at compile-time (when built).
-var _ core.Store = (*InMemory)(nil)
+var (
+	_ core.Store   = (*InMemory)(nil)
+	_ core.Counter = (*InMemory)(nil)
+)
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+)
+
+type fixedClock struct{ t time.Time }
+
+func (c *fixedClock) Now() time.Time { return c.t }
+
+func TestInMemoryClock(t *testing.T) {
+	clk := &fixedClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	m := NewInMemoryStore(time.Minute, WithClock(clk), WithoutGC())
+	ctx := context.Background()
+
+	if err := m.Put(ctx, "s", map[string]any{"k": "v"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	// the GC sweeps with the store's clock, not the wall clock
+	m.Sweep(m.now(ctx))
+	if got, _ := m.Get(ctx, "s"); got["k"] != "v" {
+		t.Fatalf("session lost after sweep: %v", got)
+	}
+
+	clk.t = clk.t.Add(2 * time.Minute)
+	if got, _ := m.Get(ctx, "s"); len(got) != 0 {
+		t.Fatalf("expired session still visible: %v", got)
+	}
+	m.Sweep(m.now(ctx))
+	if m.Len() != 0 {
+		t.Fatalf("Len() = %d after sweep, want 0", m.Len())
+	}
+}
+
+func TestInMemoryContextClockWins(t *testing.T) {
+	store := &fixedClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	req := &fixedClock{t: store.t.Add(time.Hour)}
+	m := NewInMemoryStore(time.Minute, WithClock(store), WithoutGC())
+	if got := m.now(core.WithClock(context.Background(), req)); !got.Equal(req.t) {
+		t.Fatalf("now = %v, want the context clock %v", got, req.t)
+	}
+	if got := m.now(context.Background()); !got.Equal(store.t) {
+		t.Fatalf("now = %v, want the store clock %v", got, store.t)
+	}
+}
+
+func TestInMemoryCloseStopsGC(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 50; i++ {
+		_ = NewInMemoryStore(time.Minute).Close()
+	}
+	time.Sleep(50 * time.Millisecond)
+	if after := runtime.NumGoroutine(); after > before+5 {
+		t.Fatalf("goroutines: %d before, %d after closing 50 stores", before, after)
+	}
+}
@@ -0,0 +1,66 @@
+package testkit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+)
+
+// FakeClock is a core.Clock that only moves when told to.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock returns a clock frozen at start.
+func NewFakeClock(start time.Time) *FakeClock { return &FakeClock{now: start} }
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// Set moves the clock to t.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	c.mu.Unlock()
+}
+
+var _ core.Clock = (*FakeClock)(nil)
+
+// WithClock makes every request (and session assertion) see c instead of the
+// wall clock. The engine, in-memory store, rate limiter and ACL hours all read
+// time from the request context, so c drives session TTLs and rate windows.
+// The store's background GC sees no request: build it with store.WithClock(c).
+func (s *Simulator) WithClock(c *FakeClock) *Simulator {
+	s.clock = c
+	s.ctx = core.WithClock(s.ctx, c)
+	return s
+}
+
+// Clock returns the simulator's fake clock, installing one frozen at the
+// current time if none was set.
+func (s *Simulator) Clock() *FakeClock {
+	if s.clock == nil {
+		s.WithClock(NewFakeClock(time.Now()))
+	}
+	return s.clock
+}
+
+// Advance moves the simulated time forward, e.g. past a session TTL:
+//
+//	sim.Start("+258840000001").Send("1").Advance(2 * time.Minute).Send("1").Expect("Main")
+func (s *Simulator) Advance(d time.Duration) *Simulator {
+	s.Clock().Advance(d)
+	return s
+}
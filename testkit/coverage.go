@@ -0,0 +1,191 @@
+package testkit
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grahms/cardinal/router"
+)
+
+// CoverOut names the file CoverMain writes the coverage report to (".html"
+// for HTML, otherwise text). If empty, CoverMain uses CARDINAL_COVER from the
+// environment, then a -ussdcover flag if the test binary defines one.
+var CoverOut string
+
+var coverage = router.NewCoverage()
+
+// Cover records rt's route and menu-option hits into the package-wide
+// collector, which merges every router covered during the test binary's run.
+// It returns rt so it can wrap a builder:
+//
+//	eng := core.New(testkit.Cover(buildRouter()).Mount(), cfg)
+func Cover(rt *router.Router) *router.Router {
+	rt.Cover(coverage)
+	return rt
+}
+
+// CoverMain runs the tests and then writes the coverage report named by
+// CoverOut, if any:
+//
+//	func TestMain(m *testing.M) { os.Exit(testkit.CoverMain(m)) }
+//
+//	CARDINAL_COVER=coverage.html go test ./flows
+func CoverMain(m *testing.M) int {
+	code := m.Run()
+	out := CoverOut
+	if out == "" {
+		out = os.Getenv("CARDINAL_COVER")
+	}
+	if out == "" {
+		out = flagValue("ussdcover")
+	}
+	if out != "" {
+		if err := WriteCoverage(out, coverage); err != nil {
+			fmt.Fprintf(os.Stderr, "testkit: coverage: %v\n", err)
+			if code == 0 {
+				code = 1
+			}
+		}
+	}
+	return code
+}
+
+// CollectedCoverage returns the package-wide collector fed by Cover.
+func CollectedCoverage() *router.Coverage { return coverage }
+
+// WriteCoverage writes cv to path as HTML (".html"/".htm") or text.
+func WriteCoverage(path string, cv *router.Coverage) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		err = WriteCoverageHTML(f, cv)
+	default:
+		err = WriteCoverageText(f, cv)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// coverLine is one handler or option in a report.
+type coverLine struct {
+	Kind   string // SHOW, INPUT or OPTION
+	Route  string
+	Option string
+	Hits   int
+}
+
+type coverSummary struct {
+	Lines                 []coverLine
+	Handlers, HitHandlers int
+	Options, HitOptions   int
+}
+
+func (s coverSummary) Uncovered() []coverLine {
+	var out []coverLine
+	for _, l := range s.Lines {
+		if l.Hits == 0 {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func (s coverSummary) HandlerPct() string { return pct(s.HitHandlers, s.Handlers) }
+func (s coverSummary) OptionPct() string  { return pct(s.HitOptions, s.Options) }
+
+func pct(n, d int) string {
+	if d == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(d))
+}
+
+func summarize(cv *router.Coverage) coverSummary {
+	var s coverSummary
+	for _, rc := range cv.Routes() {
+		if rc.HasShow || rc.Shows > 0 {
+			s.Lines = append(s.Lines, coverLine{Kind: "SHOW", Route: rc.Pattern, Hits: rc.Shows})
+			s.Handlers++
+			if rc.Shows > 0 {
+				s.HitHandlers++
+			}
+		}
+		if rc.HasInput || rc.Inputs > 0 {
+			s.Lines = append(s.Lines, coverLine{Kind: "INPUT", Route: rc.Pattern, Hits: rc.Inputs})
+			s.Handlers++
+			if rc.Inputs > 0 {
+				s.HitHandlers++
+			}
+		}
+		for _, o := range rc.Options {
+			s.Lines = append(s.Lines, coverLine{Kind: "OPTION", Route: rc.Pattern, Option: o.Label, Hits: o.Hits})
+			s.Options++
+			if o.Hits > 0 {
+				s.HitOptions++
+			}
+		}
+	}
+	return s
+}
+
+// WriteCoverageText writes a plain-text report: totals, every handler and
+// option with its hit count, then the uncovered ones.
+func WriteCoverageText(w io.Writer, cv *router.Coverage) error {
+	s := summarize(cv)
+	var b strings.Builder
+	fmt.Fprintf(&b, "handlers: %d/%d (%s)  options: %d/%d (%s)\n\n",
+		s.HitHandlers, s.Handlers, s.HandlerPct(), s.HitOptions, s.Options, s.OptionPct())
+	for _, l := range s.Lines {
+		if l.Kind == "OPTION" {
+			fmt.Fprintf(&b, "  %-6s %-30s %6d\n", "", l.Option, l.Hits)
+			continue
+		}
+		fmt.Fprintf(&b, "%-8s %-30s %6d\n", l.Kind, l.Route, l.Hits)
+	}
+	if un := s.Uncovered(); len(un) > 0 {
+		b.WriteString("\nuncovered:\n")
+		for _, l := range un {
+			if l.Kind == "OPTION" {
+				fmt.Fprintf(&b, "  %s %q\n", l.Route, l.Option)
+			} else {
+				fmt.Fprintf(&b, "  %s %s\n", l.Kind, l.Route)
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var coverHTML = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>USSD route coverage</title>
+<style>
+body{font-family:sans-serif;margin:2em}
+table{border-collapse:collapse}
+td,th{padding:.25em .75em;text-align:left;border-bottom:1px solid #ddd}
+td.n{text-align:right}
+tr.miss{background:#fdd}
+tr.opt td:first-child{padding-left:2em;color:#666}
+</style></head><body>
+<h1>USSD route coverage</h1>
+<p>Handlers: {{.HitHandlers}}/{{.Handlers}} ({{.HandlerPct}}) &middot; Options: {{.HitOptions}}/{{.Options}} ({{.OptionPct}})</p>
+<table>
+<tr><th>Kind</th><th>Route</th><th>Option</th><th>Hits</th></tr>
+{{range .Lines}}<tr class="{{if eq .Kind "OPTION"}}opt{{end}}{{if eq .Hits 0}} miss{{end}}"><td>{{.Kind}}</td><td>{{.Route}}</td><td>{{.Option}}</td><td class="n">{{.Hits}}</td></tr>
+{{end}}</table>
+</body></html>
+`))
+
+// WriteCoverageHTML writes a standalone HTML page; uncovered rows are highlighted.
+func WriteCoverageHTML(w io.Writer, cv *router.Coverage) error {
+	return coverHTML.Execute(w, summarize(cv))
+}
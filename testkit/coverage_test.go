@@ -0,0 +1,111 @@
+package testkit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/menu"
+	"github.com/grahms/cardinal/router"
+	"github.com/grahms/cardinal/store"
+)
+
+func coveredRouter() *router.Router {
+	r := router.New("/home")
+	home := menu.New("/home").Title("Welcome").Opt("Balance", "/balance").Opt("Buy", "/buy").End("Quit", "Bye")
+	r.SHOW("/home", home.Prompt)
+	r.INPUT("/home", home.Handle)
+	r.SHOW("/balance", func(c *router.Ctx) core.Reply { return core.END("Balance: 10") })
+	r.SHOW("/buy", func(c *router.Ctx) core.Reply { return core.CON("Amount?") })
+	r.SHOW("/users/:id", func(c *router.Ctx) core.Reply { return core.END(c.Param("id")) })
+	return r
+}
+
+func coverRun(t *testing.T, rt *router.Router, inputs ...string) {
+	t.Helper()
+	eng := core.New(rt.Mount(), core.Config{Store: store.NewInMemoryStore(time.Minute, store.WithoutGC())})
+	sim := New(t, eng).Start("+258840000001")
+	for _, in := range inputs {
+		sim.Send(in)
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	cv := router.NewCoverage()
+	rt := coveredRouter()
+	stop := rt.Cover(cv)
+	coverRun(t, rt, "1")
+	coverRun(t, rt, "9", "1") // invalid choice, then Balance again
+
+	want := []router.RouteCoverage{
+		{Pattern: "/balance", HasShow: true, Shows: 2},
+		{Pattern: "/buy", HasShow: true},
+		// "9" re-shows the menu, so it is shown three times
+		{Pattern: "/home", HasShow: true, HasInput: true, Shows: 3, Inputs: 3, Options: []router.OptionCoverage{
+			{Label: "1) Balance", Hits: 2}, {Label: "2) Buy"}, {Label: "3) Quit"},
+		}},
+		{Pattern: "/users/:id", HasShow: true},
+	}
+	if got := cv.Routes(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Routes() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	stop()
+	coverRun(t, rt, "1")
+	if got := cv.Routes()[0].Shows; got != 2 {
+		t.Fatalf("/balance shows after stop = %d, want 2", got)
+	}
+
+	// a second router merges by pattern
+	rt2 := coveredRouter()
+	defer rt2.Cover(cv)()
+	coverRun(t, rt2, "2")
+	if got := cv.Routes()[1]; got.Pattern != "/buy" || got.Shows != 1 {
+		t.Fatalf("merged /buy = %+v", got)
+	}
+
+	var text bytes.Buffer
+	if err := WriteCoverageText(&text, cv); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"handlers: 4/5 (80.0%)  options: 2/3 (66.7%)",
+		"SHOW     /users/:id",
+		"uncovered:\n  /home \"3) Quit\"\n  SHOW /users/:id\n",
+	} {
+		if !strings.Contains(text.String(), s) {
+			t.Errorf("text report lacks %q:\n%s", s, text.String())
+		}
+	}
+
+	var html bytes.Buffer
+	if err := WriteCoverageHTML(&html, cv); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"Handlers: 4/5 (80.0%) &middot; Options: 2/3 (66.7%)",
+		`<tr class="opt miss"><td>OPTION</td><td>/home</td><td>3) Quit</td><td class="n">0</td></tr>`,
+		`<tr class=" miss"><td>SHOW</td><td>/users/:id</td>`,
+	} {
+		if !strings.Contains(html.String(), s) {
+			t.Errorf("HTML report lacks %q:\n%s", s, html.String())
+		}
+	}
+
+	dir := t.TempDir()
+	for name, marker := range map[string]string{"c.html": "<!DOCTYPE html>", "c.txt": "handlers: "} {
+		path := filepath.Join(dir, name)
+		if err := WriteCoverage(path, cv); err != nil {
+			t.Fatal(err)
+		}
+		b, _ := os.ReadFile(path)
+		if !strings.HasPrefix(string(b), marker) {
+			t.Errorf("%s starts with %.40q", name, b)
+		}
+	}
+}
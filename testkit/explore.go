@@ -0,0 +1,439 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/router"
+	"github.com/grahms/cardinal/store"
+)
+
+// ExploreConfig bounds an exploration. Zero values get defaults.
+type ExploreConfig struct {
+	Start       string // start path (default: the router's)
+	Msisdn      string // default "+258840000000"
+	ServiceCode string
+	Seed        map[string]any // session data for every run (e.g. a logged-in user)
+
+	MaxDepth    int           // inputs per path (default 8)
+	MaxStates   int           // distinct screens to expand (default 500)
+	Random      int           // random inputs tried per screen (default 2)
+	RandSeed    int64         // for reproducible random inputs (default 1)
+	Inputs      []string      // extra inputs tried on every screen
+	StepTimeout time.Duration // a step running longer is reported as a loop (default 2s)
+
+	// Ignore lists route patterns that are not meant to be reachable from
+	// Start (e.g. entry points for other service codes or push sessions).
+	Ignore []string
+}
+
+// IssueKind classifies an exploration finding.
+type IssueKind int
+
+const (
+	IssuePanic       IssueKind = iota // a handler panicked
+	IssueBlank                        // CON with an empty screen
+	IssueUnavailable                  // "Service unavailable." (missing route or recovered panic)
+	IssueLoop                         // step never returned, or an option leads back to the same screen
+	IssueUnreachable                  // route never executed
+)
+
+func (k IssueKind) String() string {
+	switch k {
+	case IssuePanic:
+		return "panic"
+	case IssueBlank:
+		return "blank screen"
+	case IssueUnavailable:
+		return "service unavailable"
+	case IssueLoop:
+		return "loop"
+	case IssueUnreachable:
+		return "unreachable"
+	}
+	return "unknown"
+}
+
+// Issue is one finding, with the inputs that reproduce it from a fresh session.
+type Issue struct {
+	Kind   IssueKind
+	Path   string   // screen the inputs were sent on (route pattern for unreachable)
+	Inputs []string // from the start screen
+	Detail string
+}
+
+func (i Issue) String() string {
+	if i.Kind == IssueUnreachable {
+		return fmt.Sprintf("%s: %s", i.Kind, i.Path)
+	}
+	s := fmt.Sprintf("%s at %s after %q", i.Kind, i.Path, i.Inputs)
+	if i.Detail != "" {
+		s += ": " + i.Detail
+	}
+	return s
+}
+
+// ExploreReport is the result of Explore.
+type ExploreReport struct {
+	States    int        // distinct screens reached
+	Reached   []string   // route patterns executed, sorted
+	Sequences [][]string // input sequences leading to each distinct screen
+	Issues    []Issue
+}
+
+// OK reports whether no issues were found.
+func (r *ExploreReport) OK() bool { return len(r.Issues) == 0 }
+
+func (r *ExploreReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "explored %d screens, %d routes reached, %d issues\n", r.States, len(r.Reached), len(r.Issues))
+	for _, is := range r.Issues {
+		b.WriteString("  " + is.String() + "\n")
+	}
+	return b.String()
+}
+
+// Check fails t once per issue.
+func (r *ExploreReport) Check(t testing.TB) {
+	t.Helper()
+	for _, is := range r.Issues {
+		t.Error(is.String())
+	}
+}
+
+// Explore walks rt breadth-first from its start screen, trying every numbered
+// option on each screen plus "0", "00", an empty input, cfg.Inputs and a few
+// random strings. Each run uses a private in-memory store, so handlers with
+// side effects should be stubbed as in any unit test.
+//
+//	testkit.Explore(buildRouter(), testkit.ExploreConfig{}).Check(t)
+func Explore(rt *router.Router, cfg ExploreConfig) *ExploreReport {
+	x := newExplorer(rt, cfg)
+	var mu sync.Mutex // hung steps may still be running
+	reached := map[string]bool{}
+	untrace := rt.Trace(func(pattern string, _ bool) {
+		mu.Lock()
+		reached[pattern] = true
+		mu.Unlock()
+	})
+	defer untrace()
+	reachedRoutes := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return sortedKeys(reached)
+	}
+
+	rep := &ExploreReport{}
+	seen := map[string]bool{}
+	issued := map[string]bool{}
+	report := func(is Issue) {
+		k := fmt.Sprintf("%d|%s|%s", is.Kind, is.Path, is.Detail)
+		if !issued[k] {
+			issued[k] = true
+			rep.Issues = append(rep.Issues, is)
+		}
+	}
+
+	type node struct {
+		inputs []string
+		path   string
+		screen string
+		data   map[string]any
+	}
+	res := x.begin()
+	if is, ok := res.issue(x.startLabel(), nil); ok {
+		report(is)
+	}
+	if !res.ok || !res.reply.Continue {
+		rep.Reached = reachedRoutes()
+		return rep
+	}
+	root := node{path: router.CurrentPath(res.data), screen: res.reply.Message, data: res.data}
+	queue := []node{root}
+	seen[root.path+"\x00"+root.screen] = true
+	rep.Sequences = append(rep.Sequences, nil)
+
+	for len(queue) > 0 && rep.States < x.cfg.MaxStates {
+		n := queue[0]
+		queue = queue[1:]
+		rep.States++
+		if len(n.inputs) >= x.cfg.MaxDepth {
+			continue
+		}
+		options := menuOptions(n.screen)
+		for _, in := range x.candidates(options) {
+			inputs := append(append([]string{}, n.inputs...), in)
+			res := x.step(n.data, in)
+			if is, ok := res.issue(n.path, inputs); ok {
+				report(is)
+			}
+			if !res.ok || !res.reply.Continue {
+				continue
+			}
+			path := router.CurrentPath(res.data)
+			if contains(options, in) && path == n.path && res.reply.Message == n.screen && sameSession(res.data, n.data) {
+				report(Issue{Kind: IssueLoop, Path: n.path, Inputs: inputs, Detail: "option " + in + " leads back to the same screen"})
+			}
+			key := path + "\x00" + res.reply.Message
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			rep.Sequences = append(rep.Sequences, inputs)
+			queue = append(queue, node{inputs: inputs, path: path, screen: res.reply.Message, data: res.data})
+		}
+	}
+
+	rep.Reached = reachedRoutes()
+	skip := map[string]bool{}
+	for _, p := range append(rep.Reached, x.cfg.Ignore...) {
+		skip[p] = true
+	}
+	for _, p := range rt.Routes() {
+		if !skip[p] {
+			report(Issue{Kind: IssueUnreachable, Path: p})
+		}
+	}
+	return rep
+}
+
+// Fuzz hooks a router into Go native fuzzing. Each fuzz input is a "*"-joined
+// sequence of user inputs played from a fresh session; the corpus is seeded
+// with the sequences Explore discovers. Panics, blank screens, "Service
+// unavailable." replies and hung steps fail the run.
+//
+//	func FuzzMenus(f *testing.F) { testkit.Fuzz(f, buildRouter(), testkit.ExploreConfig{}) }
+//
+// Run with: go test -fuzz=FuzzMenus ./yourpkg
+func Fuzz(f *testing.F, rt *router.Router, cfg ExploreConfig) {
+	f.Helper()
+	for _, seq := range Explore(rt, cfg).Sequences {
+		f.Add(strings.Join(seq, "*"))
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		x := newExplorer(rt, cfg)
+		res := x.begin()
+		if is, ok := res.issue(x.startLabel(), nil); ok {
+			t.Fatal(is.String())
+		}
+		var inputs []string
+		for _, in := range strings.Split(s, "*") {
+			if !res.ok || !res.reply.Continue {
+				return
+			}
+			path := router.CurrentPath(res.data)
+			inputs = append(inputs, in)
+			res = x.step(res.data, in)
+			if is, ok := res.issue(path, inputs); ok {
+				t.Fatal(is.String())
+			}
+		}
+	})
+}
+
+/* ---------- runner ---------- */
+
+type explorer struct {
+	cfg ExploreConfig
+	eng *core.Engine
+	st  core.Store
+	rnd *rand.Rand
+	n   int
+}
+
+func newExplorer(rt *router.Router, cfg ExploreConfig) *explorer {
+	if cfg.Msisdn == "" {
+		cfg.Msisdn = "+258840000000"
+	}
+	if cfg.MaxDepth <= 0 {
+		cfg.MaxDepth = 8
+	}
+	if cfg.MaxStates <= 0 {
+		cfg.MaxStates = 500
+	}
+	if cfg.Random == 0 {
+		cfg.Random = 2
+	}
+	if cfg.RandSeed == 0 {
+		cfg.RandSeed = 1
+	}
+	if cfg.StepTimeout <= 0 {
+		cfg.StepTimeout = 2 * time.Second
+	}
+	app := rt.Mount()
+	if cfg.Start != "" {
+		app = rt.MountAt(cfg.Start)
+	}
+	// no GC goroutine: Fuzz builds an explorer per input, and the store
+	// is dropped with it
+	st := store.NewInMemoryStore(time.Hour, store.WithoutGC())
+	return &explorer{
+		cfg: cfg,
+		eng: core.New(app, core.Config{Store: st, SessionTTL: time.Hour}),
+		st:  st,
+		rnd: rand.New(rand.NewSource(cfg.RandSeed)),
+	}
+}
+
+type stepResult struct {
+	ok    bool // step returned without panicking or hanging
+	reply core.Reply
+	data  map[string]any // session after the step (nil once ended)
+	panic any
+	hung  bool
+}
+
+func (r stepResult) issue(path string, inputs []string) (Issue, bool) {
+	is := Issue{Path: path, Inputs: inputs}
+	switch {
+	case r.panic != nil:
+		is.Kind, is.Detail = IssuePanic, fmt.Sprint(r.panic)
+	case r.hung:
+		is.Kind, is.Detail = IssueLoop, "step did not return"
+	case r.reply.Message == "Service unavailable.":
+		is.Kind = IssueUnavailable
+	case r.reply.Continue && strings.TrimSpace(r.reply.Message) == "":
+		is.Kind = IssueBlank
+	default:
+		return is, false
+	}
+	return is, true
+}
+
+func (x *explorer) startLabel() string {
+	if x.cfg.Start != "" {
+		return x.cfg.Start
+	}
+	return "(start)"
+}
+
+func (x *explorer) sid() string {
+	x.n++
+	return fmt.Sprintf("explore-%d", x.n)
+}
+
+func (x *explorer) begin() stepResult {
+	return x.run(core.Request{SessionID: x.sid(), Kind: core.KindBegin}, x.cfg.Seed)
+}
+
+// step replays input on a copy of the session data, so siblings never see
+// each other's changes.
+func (x *explorer) step(data map[string]any, input string) stepResult {
+	sid := x.sid()
+	_ = x.st.Put(context.Background(), sid, data, time.Hour)
+	return x.run(core.Request{SessionID: sid, Text: input}, nil)
+}
+
+func (x *explorer) run(req core.Request, seed map[string]any) stepResult {
+	req.Msisdn, req.ServiceCode = x.cfg.Msisdn, x.cfg.ServiceCode
+	done := make(chan stepResult, 1)
+	go func() {
+		var res stepResult
+		defer func() {
+			if r := recover(); r != nil {
+				res.panic = r
+			}
+			done <- res
+		}()
+		var err error
+		if seed != nil {
+			res.reply, err = x.eng.Start(context.Background(), req, seed)
+		} else {
+			res.reply, err = x.eng.Handle(context.Background(), req)
+		}
+		res.ok = err == nil
+		if res.ok && res.reply.Continue {
+			res.data, _ = x.st.Get(context.Background(), req.SessionID)
+		}
+	}()
+	select {
+	case res := <-done:
+		return res
+	case <-time.After(x.cfg.StepTimeout):
+		return stepResult{hung: true}
+	}
+}
+
+/* ---------- inputs ---------- */
+
+var optionLine = regexp.MustCompile(`(?m)^\s*(\d+)\s*[).:\-]`)
+
+// menuOptions returns the option numbers listed on a screen ("1) Buy", "2. Sell").
+func menuOptions(screen string) []string {
+	var out []string
+	for _, m := range optionLine.FindAllStringSubmatch(screen, -1) {
+		if !contains(out, m[1]) {
+			out = append(out, m[1])
+		}
+	}
+	return out
+}
+
+const randomAlphabet = "0123456789012345678901234567890123456789abcXYZ #*-.é"
+
+func (x *explorer) candidates(options []string) []string {
+	out := append([]string{}, options...)
+	add := func(s string) {
+		if !contains(out, s) {
+			out = append(out, s)
+		}
+	}
+	for _, s := range []string{"0", "00", ""} {
+		add(s)
+	}
+	for _, s := range x.cfg.Inputs {
+		add(s)
+	}
+	alphabet := []rune(randomAlphabet)
+	for i := 0; i < x.cfg.Random; i++ {
+		n := 1 + x.rnd.Intn(12)
+		r := make([]rune, n)
+		for j := range r {
+			r[j] = alphabet[x.rnd.Intn(len(alphabet))]
+		}
+		// "*" separates tokens on the wire, so it never reaches a handler inside one
+		add(strings.ReplaceAll(string(r), "*", "#"))
+	}
+	return out
+}
+
+// sameSession compares session data, ignoring the router's pending redirect.
+func sameSession(a, b map[string]any) bool {
+	strip := func(m map[string]any) map[string]any {
+		cp := make(map[string]any, len(m))
+		for k, v := range m {
+			if k != "_next" {
+				cp[k] = v
+			}
+		}
+		return cp
+	}
+	return reflect.DeepEqual(strip(a), strip(b))
+}
+
+func contains(xs []string, s string) bool {
+	for _, x := range xs {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,118 @@
+package testkit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/router"
+)
+
+// buggyRouter has one bug of each kind Explore reports.
+func buggyRouter() *router.Router {
+	r := router.New("/home")
+	r.SHOW("/home", func(c *router.Ctx) core.Reply {
+		return core.CON("Menu\n1) Boom\n2) Blank\n3) Stay\n4) Missing\n5) Slow\n6) Fine")
+	})
+	r.INPUT("/home", func(c *router.Ctx) core.Reply {
+		switch c.In() {
+		case "1":
+			panic("boom")
+		case "2":
+			c.Redirect("/blank")
+		case "3":
+			// forgot the redirect: the option leads back to the menu
+		case "4":
+			c.Redirect("/missing")
+		case "5":
+			time.Sleep(200 * time.Millisecond)
+		case "6":
+			return core.END("Bye")
+		}
+		return core.CON("")
+	})
+	r.SHOW("/blank", func(c *router.Ctx) core.Reply { return core.CON(" ") })
+	r.INPUT("/blank", func(c *router.Ctx) core.Reply { return core.END("Bye") })
+	r.SHOW("/orphan", func(c *router.Ctx) core.Reply { return core.CON("Nobody links here") })
+	return r
+}
+
+func TestExploreFindsIssues(t *testing.T) {
+	rep := Explore(buggyRouter(), ExploreConfig{StepTimeout: 50 * time.Millisecond})
+	found := map[IssueKind][]Issue{}
+	for _, is := range rep.Issues {
+		found[is.Kind] = append(found[is.Kind], is)
+	}
+	want := []struct {
+		kind   IssueKind
+		path   string
+		inputs []string
+	}{
+		{IssuePanic, "/home", []string{"1"}},
+		{IssueBlank, "/home", []string{"2"}},
+		{IssueLoop, "/home", []string{"3"}},
+		{IssueUnavailable, "/home", []string{"4"}},
+		{IssueLoop, "/home", []string{"5"}},
+		{IssueUnreachable, "/orphan", nil},
+	}
+	for _, w := range want {
+		ok := false
+		for _, is := range found[w.kind] {
+			if is.Path == w.path && equalStrings(is.Inputs, w.inputs) {
+				ok = true
+			}
+		}
+		if !ok {
+			t.Errorf("no %s at %s after %q in:\n%s", w.kind, w.path, w.inputs, rep)
+		}
+	}
+	if rep.OK() {
+		t.Fatal("OK() with issues")
+	}
+}
+
+func TestExploreClean(t *testing.T) {
+	rep := Explore(cleanRouter(), ExploreConfig{})
+	if !rep.OK() {
+		t.Fatalf("unexpected issues:\n%s", rep)
+	}
+	if rep.States != 2 || !equalStrings(rep.Reached, []string{"/home", "/menu"}) {
+		t.Fatalf("states = %d, reached = %q", rep.States, rep.Reached)
+	}
+}
+
+// cleanRouter has a menu that leads somewhere and back.
+func cleanRouter() *router.Router {
+	r := router.New("/home")
+	r.SHOW("/home", func(c *router.Ctx) core.Reply { return core.CON("Welcome\n1) Menu") })
+	r.INPUT("/home", func(c *router.Ctx) core.Reply {
+		if c.In() == "1" {
+			c.Redirect("/menu")
+			return core.CON("")
+		}
+		return core.END("Bye")
+	})
+	r.SHOW("/menu", func(c *router.Ctx) core.Reply { return core.CON("Menu\n1) Home") })
+	r.INPUT("/menu", func(c *router.Ctx) core.Reply {
+		c.Redirect("/home")
+		return core.CON("")
+	})
+	return r
+}
+
+// FuzzCleanMenus runs the seed corpus from Explore under go test.
+func FuzzCleanMenus(f *testing.F) {
+	Fuzz(f, cleanRouter(), ExploreConfig{})
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
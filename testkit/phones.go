@@ -0,0 +1,170 @@
+package testkit
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+)
+
+// Phones simulates several subscribers against one engine, for flows that
+// involve more than one user (transfers between phones, shared limits).
+// Each phone is a full Simulator with its own session:
+//
+//	ph := testkit.NewPhones(t, eng)
+//	alice := ph.Add("alice", "+258840000001")
+//	bob := ph.Add("bob", "+258840000002")
+//	alice.Start(alice.Msisdn()).Send("2").Send("+258840000002").Send("100").ExpectEndsWith("Sent")
+//	bob.Start(bob.Msisdn()).Send("1").ExpectEndsWith("Balance: 100")
+//
+// Session IDs are deterministic ("sess-alice-1", "sess-alice-2", ...).
+type Phones struct {
+	t      *testing.T
+	eng    core.Handler
+	phones map[string]*Simulator
+	order  []string
+	clock  *FakeClock
+}
+
+// NewPhones returns an empty set of phones sharing eng.
+func NewPhones(t *testing.T, eng core.Handler) *Phones {
+	return &Phones{t: t, eng: eng, phones: map[string]*Simulator{}}
+}
+
+// Add registers a phone. Failures it reports are prefixed with its name.
+func (p *Phones) Add(name, msisdn string, service ...string) *Simulator {
+	p.t.Helper()
+	if _, dup := p.phones[name]; dup {
+		p.t.Fatalf("phones: %q added twice", name)
+	}
+	s := New(p.t, p.eng)
+	s.t = namedT{TB: p.t, name: name}
+	s.name = name
+	s.msisdn = msisdn
+	if len(service) > 0 {
+		s.service = service[0]
+	}
+	if p.clock != nil {
+		s.WithClock(p.clock)
+	}
+	p.phones[name] = s
+	p.order = append(p.order, name)
+	return s
+}
+
+// Phone returns the phone registered as name.
+func (p *Phones) Phone(name string) *Simulator {
+	p.t.Helper()
+	s, ok := p.phones[name]
+	if !ok {
+		p.t.Fatalf("phones: no phone %q", name)
+	}
+	return s
+}
+
+// WithClock shares one fake clock between all phones, including ones added later.
+func (p *Phones) WithClock(c *FakeClock) *Phones {
+	p.clock = c
+	for _, s := range p.phones {
+		s.WithClock(c)
+	}
+	return p
+}
+
+// Advance moves the shared clock forward (installing one if needed).
+func (p *Phones) Advance(d time.Duration) *Phones {
+	if p.clock == nil {
+		p.WithClock(NewFakeClock(time.Now()))
+	}
+	p.clock.Advance(d)
+	return p
+}
+
+// Turn is one step by one phone in an interleaved script.
+type Turn struct {
+	Phone string `json:"phone" yaml:"phone"`
+	Step  `yaml:",inline"`
+}
+
+// Run plays turns in order. A phone without an open session starts one
+// first, so a turn with empty Input asserts its start screen.
+//
+//	ph.Run([]testkit.Turn{
+//	    {Phone: "alice", Step: testkit.Step{Expect: "Welcome"}},
+//	    {Phone: "bob", Step: testkit.Step{Expect: "Welcome"}},
+//	    {Phone: "alice", Step: testkit.Step{Input: "1", Expect: "Balance"}},
+//	})
+func (p *Phones) Run(turns []Turn) *Phones {
+	p.t.Helper()
+	for _, tr := range turns {
+		s := p.Phone(tr.Phone)
+		s.ensureSession()
+		s.Run([]Step{tr.Step})
+	}
+	return p
+}
+
+// Concurrently runs each phone's steps at the same time, one parallel subtest
+// per phone, and returns when all are done. Phones without an open session
+// start one first. Run tests with -race to catch unsafe shared state in
+// handlers, stores and middleware.
+//
+//	ph.Concurrently(map[string][]testkit.Step{
+//	    "alice": {{Input: "1"}, {Input: "100", End: true}},
+//	    "bob":   {{Input: "1"}, {Input: "100", End: true}},
+//	})
+func (p *Phones) Concurrently(scripts map[string][]Step) *Phones {
+	p.t.Helper()
+	names := make([]string, 0, len(scripts))
+	for name := range scripts {
+		p.Phone(name) // fail early on typos
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	p.t.Run("concurrently", func(t *testing.T) {
+		for _, name := range names {
+			name, s, steps := name, p.phones[name], scripts[name]
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				s.t = namedT{TB: t, name: name}
+				s.ensureSession()
+				s.Run(steps)
+			})
+		}
+	})
+	for _, name := range names {
+		p.phones[name].t = namedT{TB: p.t, name: name}
+	}
+	return p
+}
+
+// Names lists the phones in the order they were added.
+func (p *Phones) Names() []string { return append([]string(nil), p.order...) }
+
+// Msisdn returns the number the simulator dials from.
+func (s *Simulator) Msisdn() string { return s.msisdn }
+
+// ensureSession starts a session unless one is open.
+func (s *Simulator) ensureSession() {
+	if s.session == "" || !s.last.Continue {
+		s.Start(s.msisdn, s.service)
+	}
+}
+
+// namedT prefixes failures with the phone's name.
+type namedT struct {
+	testing.TB
+	name string
+}
+
+func (n namedT) Fatalf(format string, args ...any) {
+	n.TB.Helper()
+	n.TB.Fatalf("[%s] %s", n.name, fmt.Sprintf(format, args...))
+}
+
+func (n namedT) Errorf(format string, args ...any) {
+	n.TB.Helper()
+	n.TB.Errorf("[%s] %s", n.name, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,98 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/record"
+)
+
+// ReplaySecret is typed wherever a recording has a redacted input (a PIN).
+// Seed your test store so this value is accepted.
+var ReplaySecret = "0000"
+
+// ReplayDiff is a screen that differs from the recording.
+type ReplayDiff struct {
+	Step int    // 1-based index into Recording.Steps
+	Text string // request text sent
+	Want string // recorded raw screen ("CON ..."/"END ...")
+	Got  string
+}
+
+func (d ReplayDiff) String() string {
+	return fmt.Sprintf("step %d (text %q):\n%s", d.Step, d.Text,
+		UnifiedDiff("recorded", "replayed", d.Want+"\n", d.Got+"\n"))
+}
+
+// Replay feeds a recording into h as a fresh session and returns every step
+// whose screen differs. Replaying continues after a difference, so a single
+// changed label shows up once rather than cascading into a failed run.
+func Replay(ctx context.Context, h core.Handler, rec record.Recording) ([]ReplayDiff, error) {
+	var diffs []ReplayDiff
+	for i, ex := range rec.Steps {
+		req := core.Request{
+			SessionID:   rec.SessionID,
+			Msisdn:      rec.Msisdn,
+			ServiceCode: rec.ServiceCode,
+			Text:        unredact(ex.Text),
+			Kind:        kind(ex.Kind),
+		}
+		rep, err := h.Handle(ctx, req)
+		if err != nil && ex.Error == "" {
+			return diffs, fmt.Errorf("step %d (text %q): %w", i+1, req.Text, err)
+		}
+		want := raw(core.Reply{Continue: ex.Continue, Message: ex.Message})
+		if got := raw(rep); got != want {
+			diffs = append(diffs, ReplayDiff{Step: i + 1, Text: req.Text, Want: want, Got: got})
+		}
+	}
+	return diffs, nil
+}
+
+// ReplayFile replays every recording in a record.FileSink file as a subtest,
+// each against a fresh handler from build, and reports the screens that differ.
+//
+//	func TestReplayIncident(t *testing.T) {
+//	    testkit.ReplayFile(t, "testdata/incident-4711.jsonl", BuildEngineForTests)
+//	}
+func ReplayFile[H core.Handler](t *testing.T, path string, build func() H) {
+	t.Helper()
+	recs, err := record.ReadFile(path)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if len(recs) == 0 {
+		t.Fatalf("replay: no recordings in %s", path)
+	}
+	for _, rec := range recs {
+		rec := rec
+		t.Run(rec.SessionID, func(t *testing.T) {
+			diffs, err := Replay(context.Background(), build(), rec)
+			for _, d := range diffs {
+				t.Error(d.String())
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// kind reads Exchange.Kind, which is empty for unspecified.
+func kind(s string) core.Kind {
+	k, _ := core.ParseKind(s)
+	return k
+}
+
+func unredact(text string) string {
+	toks := strings.Split(text, "*")
+	for i, tok := range toks {
+		if tok == record.Redacted {
+			toks[i] = ReplaySecret
+		}
+	}
+	return strings.Join(toks, "*")
+}
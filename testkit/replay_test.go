@@ -0,0 +1,68 @@
+package testkit
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/record"
+	"github.com/grahms/cardinal/router"
+	"github.com/grahms/cardinal/store"
+)
+
+// pinEngine accepts PIN 4321 and confirms with the given verb.
+func pinEngine(verb string) func() *core.Engine {
+	return func() *core.Engine {
+		r := router.New("/home")
+		r.SHOW("/home", func(c *router.Ctx) core.Reply { return core.CON("Enter PIN") })
+		r.INPUT("/home", func(c *router.Ctx) core.Reply {
+			if c.In() != "4321" {
+				return core.END("Wrong PIN")
+			}
+			c.Redirect("/amount")
+			return core.CON("")
+		})
+		r.SHOW("/amount", func(c *router.Ctx) core.Reply { return core.CON("Amount") })
+		r.INPUT("/amount", func(c *router.Ctx) core.Reply { return core.END(verb + " " + c.In()) })
+		return core.New(r.Mount(), core.Config{Store: store.NewInMemoryStore(time.Minute, store.WithoutGC())})
+	}
+}
+
+func TestReplayFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.jsonl")
+	sink, err := record.NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := record.New(pinEngine("Sent")(), record.Config{Sink: sink})
+	ctx := context.Background()
+	for _, text := range []string{"", "4321", "4321"} { // the amount equals the PIN
+		if _, err := rec.Handle(ctx, core.Request{SessionID: "s1", Msisdn: "+258840000001", Text: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(s string) { ReplaySecret = s }(ReplaySecret)
+	ReplaySecret = "4321"
+	ReplayFile(t, path, pinEngine("Sent"))
+
+	recs, err := record.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs, err := Replay(ctx, pinEngine("Transferred")(), recs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 1 || diffs[0].Step != 3 || diffs[0].Text != "4321" {
+		t.Fatalf("diffs = %+v, want one at step 3 for text 4321", diffs)
+	}
+	if diffs[0].Want != "END Sent 4321" || diffs[0].Got != "END Transferred 4321" {
+		t.Fatalf("diff = %+v", diffs[0])
+	}
+}
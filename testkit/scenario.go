@@ -0,0 +1,144 @@
+package testkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/grahms/cardinal/core"
+)
+
+// Scenario is a scripted flow that QA can write without Go.
+//
+//	{
+//	  "name": "top-up 100",
+//	  "msisdn": "+258840000001",
+//	  "serviceCode": "*144#",
+//	  "steps": [
+//	    {"expect": "Welcome"},
+//	    {"input": "1", "expect": "Enter amount"},
+//	    {"input": "100", "match": "^Top-up (successful|queued)", "end": true}
+//	  ]
+//	}
+type Scenario struct {
+	Name        string `json:"name" yaml:"name"`
+	Msisdn      string `json:"msisdn" yaml:"msisdn"`
+	ServiceCode string `json:"serviceCode" yaml:"serviceCode"`
+	Steps       []Step `json:"steps" yaml:"steps"`
+}
+
+// Unmarshalers decode scenario files by extension. JSON is built in; register
+// your YAML library to use .yaml files without Cardinal depending on one:
+//
+//	testkit.Unmarshalers[".yaml"] = yaml.Unmarshal
+//	testkit.Unmarshalers[".yml"] = yaml.Unmarshal
+var Unmarshalers = map[string]func([]byte, any) error{
+	".json": json.Unmarshal,
+}
+
+// Run plays steps in order, failing the test at the first mismatch.
+func (s *Simulator) Run(steps []Step) *Simulator {
+	s.t.Helper()
+	for i, st := range steps {
+		if st.Input != "" {
+			s.Send(st.Input)
+		}
+		if st.End == s.last.Continue {
+			want, got := "CON", "END"
+			if st.End {
+				want, got = "END", "CON"
+			}
+			s.t.Fatalf("step %d (input %q): expected %s, got %s: %q", i+1, st.Input, want, got, s.last.Message)
+		}
+		if st.Expect != "" && !strings.Contains(s.last.Message, st.Expect) {
+			s.t.Fatalf("step %d (input %q): expected substring %q in %q", i+1, st.Input, st.Expect, s.last.Message)
+		}
+		if st.Match != "" {
+			re, err := regexp.Compile(st.Match)
+			if err != nil {
+				s.t.Fatalf("step %d: bad match %q: %v", i+1, st.Match, err)
+			}
+			if !re.MatchString(s.last.Message) {
+				s.t.Fatalf("step %d (input %q): screen %q does not match %q", i+1, st.Input, s.last.Message, st.Match)
+			}
+		}
+	}
+	return s
+}
+
+// LoadScenarios reads one scenario or a list of scenarios from a file.
+// Unnamed scenarios are named after the file (with an index for lists).
+func LoadScenarios(path string) ([]Scenario, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	unmarshal, ok := Unmarshalers[ext]
+	if !ok {
+		return nil, fmt.Errorf("testkit: no unmarshaler for %q", ext)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var list []Scenario
+	if err := unmarshal(b, &list); err != nil {
+		var one Scenario
+		if err2 := unmarshal(b, &one); err2 != nil {
+			return nil, fmt.Errorf("testkit: %s: %w", path, err2)
+		}
+		list = []Scenario{one}
+	}
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for i := range list {
+		if list[i].Name == "" {
+			list[i].Name = base
+			if len(list) > 1 {
+				list[i].Name = fmt.Sprintf("%s#%d", base, i+1)
+			}
+		}
+	}
+	return list, nil
+}
+
+// RunScenario starts a fresh session and plays sc.
+func (s *Simulator) RunScenario(sc Scenario) *Simulator {
+	s.t.Helper()
+	return s.Start(sc.Msisdn, sc.ServiceCode).Run(sc.Steps)
+}
+
+// RunDir runs every scenario file in dir as a subtest. build is called per
+// scenario so each one gets a fresh engine and store.
+//
+//	func TestScenarios(t *testing.T) {
+//	    testkit.RunDir(t, "testdata/scenarios", BuildEngineForTests)
+//	}
+func RunDir[H core.Handler](t *testing.T, dir string, build func() H) {
+	t.Helper()
+	var files []string
+	for ext := range Unmarshalers {
+		m, err := filepath.Glob(filepath.Join(dir, "*"+ext))
+		if err != nil {
+			t.Fatalf("testkit: %v", err)
+		}
+		files = append(files, m...)
+	}
+	sort.Strings(files)
+	if len(files) == 0 {
+		t.Fatalf("testkit: no scenario files in %s", dir)
+	}
+	for _, f := range files {
+		scs, err := LoadScenarios(f)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		for _, sc := range scs {
+			sc := sc
+			t.Run(sc.Name, func(t *testing.T) {
+				New(t, build()).RunScenario(sc)
+			})
+		}
+	}
+}
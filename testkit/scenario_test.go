@@ -0,0 +1,27 @@
+package testkit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// YAML libraries lowercase untagged field names ("servicecode"), so every
+// scenario field needs a yaml tag matching its json one.
+func TestScenarioYAMLTags(t *testing.T) {
+	for _, typ := range []reflect.Type{reflect.TypeOf(Scenario{}), reflect.TypeOf(Step{}), reflect.TypeOf(Turn{})} {
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			js, y := f.Tag.Get("json"), f.Tag.Get("yaml")
+			if f.Anonymous {
+				if y != ",inline" {
+					t.Errorf("%s.%s: yaml tag %q, want \",inline\"", typ.Name(), f.Name, y)
+				}
+				continue
+			}
+			if js == "" || strings.Split(y, ",")[0] != strings.Split(js, ",")[0] {
+				t.Errorf("%s.%s: json tag %q, yaml tag %q", typ.Name(), f.Name, js, y)
+			}
+		}
+	}
+}
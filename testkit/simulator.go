@@ -5,31 +5,42 @@ import (
 	"fmt"
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/router"
 )
 
-// Step is optional if you want to predefine scripted flows.
+// Step is one scripted exchange for Run and scenario files.
+// A step with empty Input asserts the current screen without sending anything.
 type Step struct {
-	Input  string // what the user types (last token)
-	Expect string // substring expected in the next screen
-	End    bool   // whether we expect the session to end
+	Input  string `json:"input" yaml:"input"`   // what the user types (last token)
+	Expect string `json:"expect" yaml:"expect"` // substring expected in the next screen
+	Match  string `json:"match" yaml:"match"`   // optional regexp the next screen must match
+	End    bool   `json:"end" yaml:"end"`       // whether we expect the session to end
 }
 
 // Simulator drives the Engine with fake requests and lets you assert responses.
 type Simulator struct {
-	t       *testing.T
-	eng     *core.Engine
+	t       testing.TB
+	eng     core.Handler
 	session string
 	msisdn  string
 	service string
 	last    core.Reply
 	ctx     context.Context
+	script  []string // transcript of inputs and raw screens (see Snapshot)
+
+	seed  map[string]any // session data for the next Start
+	store core.Store     // for session assertions (default: the engine's)
+	rt    *router.Router // for ExpectParam
+	clock *FakeClock     // set by WithClock/Advance
+
+	name string // phone name for session IDs (default: the MSISDN)
+	n    int    // sessions started, for deterministic session IDs
 }
 
 // New builds a simulator around an Engine.
-func New(t *testing.T, eng *core.Engine) *Simulator {
+func New(t *testing.T, eng core.Handler) *Simulator {
 	return &Simulator{
 		t:   t,
 		eng: eng,
@@ -38,24 +49,45 @@ func New(t *testing.T, eng *core.Engine) *Simulator {
 }
 
 // Start begins a session with an MSISDN (and optional service code).
+// Session IDs are deterministic: "sess-<msisdn or phone name>-<n>".
 func (s *Simulator) Start(msisdn string, service ...string) *Simulator {
-	s.session = fmt.Sprintf("sess-%d", time.Now().UnixNano())
 	s.msisdn = msisdn
+	s.n++
+	id := s.name
+	if id == "" {
+		id = msisdn
+	}
+	s.session = fmt.Sprintf("sess-%s-%d", id, s.n)
 	if len(service) > 0 {
 		s.service = service[0]
 	}
 
-	// First call: empty text triggers SHOW of start path.
-	rep, err := s.eng.Handle(s.ctx, core.Request{
+	// First call: empty text triggers SHOW of start path. KindBegin makes the
+	// engine ignore data left under the same ID by another simulator.
+	req := core.Request{
 		SessionID:   s.session,
 		Msisdn:      s.msisdn,
 		ServiceCode: s.service,
 		Text:        "",
-	})
+		Kind:        core.KindBegin,
+	}
+	var rep core.Reply
+	var err error
+	if s.seed != nil {
+		st, ok := s.eng.(starter)
+		if !ok {
+			s.t.Fatalf("start: seeding needs a *core.Engine, got %T", s.eng)
+		}
+		rep, err = st.Start(s.ctx, req, s.seed)
+		s.seed = nil
+	} else {
+		rep, err = s.eng.Handle(s.ctx, req)
+	}
 	if err != nil {
 		s.t.Fatalf("start: %v", err)
 	}
 	s.last = rep
+	s.script = []string{"> (start " + strings.TrimSpace(s.msisdn+" "+s.service) + ")", raw(rep)}
 	return s
 }
 
@@ -71,6 +103,7 @@ func (s *Simulator) Send(token string) *Simulator {
 		s.t.Fatalf("send(%q): %v", token, err)
 	}
 	s.last = rep
+	s.script = append(s.script, "> "+token, raw(rep))
 	return s
 }
 
@@ -95,3 +128,10 @@ func (s *Simulator) ExpectEndsWith(substr string) *Simulator {
 	}
 	return s
 }
+
+func raw(r core.Reply) string {
+	if r.Continue {
+		return "CON " + r.Message
+	}
+	return "END " + r.Message
+}
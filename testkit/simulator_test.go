@@ -0,0 +1,29 @@
+package testkit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/router"
+	"github.com/grahms/cardinal/store"
+)
+
+func deepEngine() *core.Engine {
+	r := router.New("/home")
+	r.SHOW("/home", func(c *router.Ctx) core.Reply { return core.CON("Welcome\n1) Deep") })
+	r.INPUT("/home", func(c *router.Ctx) core.Reply {
+		c.Redirect("/deep")
+		return core.CON("")
+	})
+	r.SHOW("/deep", func(c *router.Ctx) core.Reply { return core.CON("Deep screen") })
+	return core.New(r.Mount(), core.Config{Store: store.NewInMemoryStore(time.Minute, store.WithoutGC())})
+}
+
+// Session IDs are deterministic, so a second simulator dialling from the same
+// MSISDN reuses the first one's ID and must not resume its session.
+func TestStartIgnoresStaleSession(t *testing.T) {
+	eng := deepEngine()
+	New(t, eng).Start("+258840000001").Send("1").Expect("Deep screen")
+	New(t, eng).Start("+258840000001").Expect("Welcome")
+}
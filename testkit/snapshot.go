@@ -0,0 +1,154 @@
+package testkit
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Update makes Snapshot rewrite golden files instead of comparing them. It is
+// also on with CARDINAL_UPDATE=1 in the environment, or when the test binary
+// defines its own -update flag and it is set. testkit registers no flags, so
+// it never clashes with a package's existing -update:
+//
+//	var _ = flag.Bool("update", false, "rewrite golden files") // in a _test.go
+var Update bool
+
+func updating() bool {
+	return Update || os.Getenv("CARDINAL_UPDATE") == "1" || flagValue("update") == "true"
+}
+
+// flagValue reads a flag the test binary may define, after flag.Parse.
+func flagValue(name string) string {
+	if f := flag.Lookup(name); f != nil {
+		return f.Value.String()
+	}
+	return ""
+}
+
+// GoldenDir is where Snapshot keeps golden files, relative to the test's package.
+var GoldenDir = "testdata"
+
+// Transcript returns every input ("> 1") and raw screen ("CON ...", "END ...")
+// since Start, in order.
+func (s *Simulator) Transcript() string {
+	return strings.Join(s.script, "\n") + "\n"
+}
+
+// Snapshot compares the transcript with GoldenDir/<name>.golden and fails with
+// a unified diff on mismatch. Set Update (or CARDINAL_UPDATE=1) to (re)write
+// golden files.
+//
+//	sim.Start("+258840000001").Send("1").Send("100").Snapshot("topup")
+func (s *Simulator) Snapshot(name string) *Simulator {
+	s.t.Helper()
+	path := filepath.Join(GoldenDir, name+".golden")
+	got := s.Transcript()
+	if updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			s.t.Fatalf("snapshot: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			s.t.Fatalf("snapshot: %v", err)
+		}
+		return s
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		s.t.Fatalf("snapshot %s: %v (set CARDINAL_UPDATE=1 to create it)", name, err)
+	}
+	if string(want) != got {
+		s.t.Fatalf("snapshot %s mismatch (set CARDINAL_UPDATE=1 to accept):\n%s", name, UnifiedDiff(path, "transcript", string(want), got))
+	}
+	return s
+}
+
+// UnifiedDiff renders a line diff of a and b with three lines of context.
+func UnifiedDiff(nameA, nameB, a, b string) string {
+	x := strings.Split(strings.TrimSuffix(a, "\n"), "\n")
+	y := strings.Split(strings.TrimSuffix(b, "\n"), "\n")
+
+	// LCS table; transcripts are small enough for O(n*m).
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type op struct {
+		kind byte // ' ', '-', '+'
+		text string
+		ai   int // 1-based line in a (for ' ' and '-')
+		bi   int // 1-based line in b (for ' ' and '+')
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			ops = append(ops, op{' ', x[i], i + 1, j + 1})
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{'-', x[i], i + 1, j + 1})
+			i++
+		default:
+			ops = append(ops, op{'+', y[j], i + 1, j + 1})
+			j++
+		}
+	}
+
+	const ctx = 3
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", nameA, nameB)
+	for k := 0; k < len(ops); {
+		if ops[k].kind == ' ' {
+			k++
+			continue
+		}
+		start := max(k-ctx, 0)
+		end := k
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run-end > 2*ctx || run == len(ops) {
+				end = min(end+ctx, len(ops))
+				break
+			}
+			end = run
+		}
+		na, nb := 0, 0
+		for _, o := range ops[start:end] {
+			if o.kind != '+' {
+				na++
+			}
+			if o.kind != '-' {
+				nb++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", ops[start].ai, na, ops[start].bi, nb)
+		for _, o := range ops[start:end] {
+			out.WriteByte(o.kind)
+			out.WriteString(o.text)
+			out.WriteByte('\n')
+		}
+		k = end
+	}
+	return out.String()
+}
@@ -0,0 +1,73 @@
+package testkit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/router"
+	"github.com/grahms/cardinal/store"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name, a, b, want string
+	}{
+		{
+			name: "equal",
+			a:    "a\nb\nc\n", b: "a\nb\nc\n",
+			want: "--- want\n+++ got\n",
+		},
+		{
+			name: "changed last line",
+			a:    "CON Welcome\n> 1\nCON Enter amount\n",
+			b:    "CON Welcome\n> 1\nCON Enter value\n",
+			want: "--- want\n+++ got\n@@ -1,3 +1,3 @@\n CON Welcome\n > 1\n-CON Enter amount\n+CON Enter value\n",
+		},
+		{
+			name: "two hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			b:    "1\nX\n3\n4\n5\n6\n7\n8\n9\n10\nY\n12\n",
+			want: "--- want\n+++ got\n@@ -1,5 +1,5 @@\n 1\n-2\n+X\n 3\n 4\n 5\n@@ -8,5 +8,5 @@\n 8\n 9\n 10\n-11\n+Y\n 12\n",
+		},
+		{
+			name: "deleted line",
+			a:    "a\nb\n", b: "b\n",
+			want: "--- want\n+++ got\n@@ -1,2 +1,1 @@\n-a\n b\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff("want", "got", tt.a, tt.b); got != tt.want {
+				t.Errorf("UnifiedDiff:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnapshotUpdate(t *testing.T) {
+	r := router.New("/home")
+	r.SHOW("/home", func(c *router.Ctx) core.Reply { return core.CON("Welcome\n1) Balance") })
+	r.INPUT("/home", func(c *router.Ctx) core.Reply { return core.END("Balance: 10") })
+	eng := core.New(r.Mount(), core.Config{Store: store.NewInMemoryStore(time.Minute, store.WithoutGC())})
+
+	dir, upd := GoldenDir, Update
+	t.Cleanup(func() { GoldenDir, Update = dir, upd })
+	GoldenDir = t.TempDir()
+
+	Update = true
+	New(t, eng).Start("+258840000001").Send("1").Snapshot("balance")
+	b, err := os.ReadFile(filepath.Join(GoldenDir, "balance.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "> (start +258840000001)\nCON Welcome\n1) Balance\n> 1\nEND Balance: 10\n"
+	if string(b) != want {
+		t.Fatalf("golden file:\n%s\nwant:\n%s", b, want)
+	}
+
+	Update = false
+	New(t, eng).Start("+258840000001").Send("1").Snapshot("balance")
+}
@@ -0,0 +1,103 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/router"
+)
+
+// starter is implemented by *core.Engine.
+type starter interface {
+	Start(ctx context.Context, req core.Request, seed map[string]any) (core.Reply, error)
+}
+
+// WithStore sets the store used by session assertions. Needed only when the
+// handler is not a *core.Engine (which exposes its own store).
+func (s *Simulator) WithStore(st core.Store) *Simulator { s.store = st; return s }
+
+// WithRouter enables ExpectParam by letting the simulator resolve route patterns.
+func (s *Simulator) WithRouter(rt *router.Router) *Simulator { s.rt = rt; return s }
+
+// Seed pre-loads session data for the next Start, so handlers deep in a flow
+// can be tested without clicking through the menus that normally set it.
+func (s *Simulator) Seed(data map[string]any) *Simulator {
+	if s.seed == nil {
+		s.seed = map[string]any{}
+	}
+	for k, v := range data {
+		s.seed[k] = v
+	}
+	return s
+}
+
+// StartAt begins a session directly on path (plus any Seed data).
+//
+//	sim.Seed(map[string]any{"amount": 500}).StartAt("/confirm/data/2", "+258840000001")
+func (s *Simulator) StartAt(path, msisdn string, service ...string) *Simulator {
+	s.seed = router.StartAt(path, s.seed)
+	return s.Start(msisdn, service...)
+}
+
+// Session returns the session data currently persisted in the store
+// (empty once the session has ended).
+func (s *Simulator) Session() map[string]any {
+	s.t.Helper()
+	st := s.store
+	if st == nil {
+		if e, ok := s.eng.(interface{ Store() core.Store }); ok {
+			st = e.Store()
+		}
+	}
+	if st == nil {
+		s.t.Fatalf("session: no store; use WithStore")
+	}
+	data, err := st.Get(s.ctx, s.session)
+	if err != nil {
+		s.t.Fatalf("session: %v", err)
+	}
+	return data
+}
+
+// ExpectPath asserts the screen the session is currently on.
+func (s *Simulator) ExpectPath(path string) *Simulator {
+	s.t.Helper()
+	if got := router.CurrentPath(s.Session()); got != path {
+		s.t.Fatalf("expected path %q, got %q", path, got)
+	}
+	return s
+}
+
+// ExpectSession asserts a session value. Values are compared with
+// reflect.DeepEqual, falling back to their printed form (so 5 matches a
+// store that round-trips numbers as float64).
+func (s *Simulator) ExpectSession(key string, want any) *Simulator {
+	s.t.Helper()
+	got, ok := s.Session()[key]
+	if !ok {
+		s.t.Fatalf("expected session[%q] = %v, but it is not set", key, want)
+	}
+	if !reflect.DeepEqual(got, want) && fmt.Sprint(got) != fmt.Sprint(want) {
+		s.t.Fatalf("expected session[%q] = %v (%T), got %v (%T)", key, want, want, got, got)
+	}
+	return s
+}
+
+// ExpectParam asserts a route parameter of the current screen (needs WithRouter).
+func (s *Simulator) ExpectParam(key, want string) *Simulator {
+	s.t.Helper()
+	if s.rt == nil {
+		s.t.Fatalf("expect param: call WithRouter first")
+	}
+	path := router.CurrentPath(s.Session())
+	params, ok := s.rt.Params(path)
+	if !ok {
+		s.t.Fatalf("expect param: no route matches %q", path)
+	}
+	if got := params[key]; got != want {
+		s.t.Fatalf("expected param %q = %q on %q, got %q", key, want, path, got)
+	}
+	return s
+}
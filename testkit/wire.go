@@ -0,0 +1,270 @@
+package testkit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/transport"
+)
+
+// WireCall is one simulated gateway request before encoding.
+type WireCall struct {
+	SessionID   string
+	Msisdn      string
+	ServiceCode string
+	Text        string // last token, or "1*100*1" when the wire accumulates
+	First       bool   // first request of the session
+}
+
+// Wire speaks one gateway's HTTP format, so a Simulator can drive the
+// transport handler instead of the engine and catch parsing/formatting bugs.
+type Wire struct {
+	Name       string
+	Accumulate bool // gateway sends the whole input history joined by "*"
+
+	// Mount builds the vendor handler with its default options.
+	Mount func(h core.Handler) http.Handler
+	// Encode turns a call into the request the gateway would send.
+	Encode func(c WireCall) *http.Request
+	// Decode reads the handler's response back into a reply.
+	Decode func(res *http.Response) (core.Reply, error)
+}
+
+// LastToken returns a copy of w that sends only the latest input.
+func (w Wire) LastToken() Wire {
+	w.Name += "-last-token"
+	w.Accumulate = false
+	return w
+}
+
+// Accumulated returns a copy of w that sends the whole input history ("1*100*1").
+func (w Wire) Accumulated() Wire {
+	w.Name += "-accumulated"
+	w.Accumulate = true
+	return w
+}
+
+// WireClient is a core.Handler that talks to an http.Handler through a Wire.
+type WireClient struct {
+	h http.Handler
+	w Wire
+
+	mu   sync.Mutex
+	hist map[string][]string
+}
+
+var _ core.Handler = (*WireClient)(nil)
+
+// Over wraps a vendor handler so the Simulator speaks its wire format:
+//
+//	at := transport.AfricaTalkingHandler(eng)
+//	testkit.New(t, testkit.Over(at, testkit.AfricasTalking)).Start("+258840000001").Expect("Welcome")
+//
+// Requests go through httptest in-process and carry the simulator's context,
+// so a fake clock (see Advance) still applies.
+func Over(h http.Handler, w Wire) *WireClient {
+	return &WireClient{h: h, w: w, hist: map[string][]string{}}
+}
+
+func (c *WireClient) Handle(ctx context.Context, req core.Request) (core.Reply, error) {
+	c.mu.Lock()
+	hist, seen := c.hist[req.SessionID]
+	if req.Text != "" {
+		hist = append(hist, req.Text)
+	}
+	c.hist[req.SessionID] = hist
+	c.mu.Unlock()
+
+	call := WireCall{
+		SessionID:   req.SessionID,
+		Msisdn:      req.Msisdn,
+		ServiceCode: req.ServiceCode,
+		Text:        req.Text,
+		First:       !seen,
+	}
+	if c.w.Accumulate {
+		call.Text = strings.Join(hist, "*")
+	}
+
+	rec := httptest.NewRecorder()
+	c.h.ServeHTTP(rec, c.w.Encode(call).WithContext(ctx))
+	res := rec.Result()
+	if res.StatusCode >= 400 {
+		b, _ := io.ReadAll(res.Body)
+		return core.Reply{}, fmt.Errorf("%s: status %d: %s", c.w.Name, res.StatusCode, strings.TrimSpace(string(b)))
+	}
+	rep, err := c.w.Decode(res)
+	if err != nil {
+		return rep, fmt.Errorf("%s: %w", c.w.Name, err)
+	}
+	if !rep.Continue {
+		c.mu.Lock()
+		delete(c.hist, req.SessionID)
+		c.mu.Unlock()
+	}
+	return rep, nil
+}
+
+// RunWires plays sc once per wire as subtests, each against a fresh engine
+// mounted behind that wire's handler.
+//
+//	testkit.RunWires(t, sc, BuildEngineForTests, testkit.Wires...)
+func RunWires[H core.Handler](t *testing.T, sc Scenario, build func() H, wires ...Wire) {
+	t.Helper()
+	for _, w := range wires {
+		w := w
+		t.Run(w.Name, func(t *testing.T) {
+			eng := build()
+			sim := New(t, Over(w.Mount(eng), w))
+			if e, ok := any(eng).(interface{ Store() core.Store }); ok {
+				sim.WithStore(e.Store())
+			}
+			sim.RunScenario(sc)
+		})
+	}
+}
+
+/* ---------- built-in wires ---------- */
+
+// Wires lists the built-in vendor wires, for running a scenario against every adapter.
+var Wires = []Wire{AfricasTalking, Infobip, Vodacom, MTN, GenericHTTP}
+
+// AfricasTalking posts form fields and accumulates input, as AT does.
+var AfricasTalking = Wire{
+	Name:       "africastalking",
+	Accumulate: true,
+	Mount:      func(h core.Handler) http.Handler { return transport.AfricaTalkingHandler(h) },
+	Encode: func(c WireCall) *http.Request {
+		return formRequest(url.Values{
+			"sessionId":   {c.SessionID},
+			"phoneNumber": {c.Msisdn},
+			"serviceCode": {c.ServiceCode},
+			"text":        {c.Text},
+		})
+	},
+	Decode: decodePrefixed,
+}
+
+// Infobip posts form fields with the latest input in USSD_STRING.
+var Infobip = Wire{
+	Name:  "infobip",
+	Mount: func(h core.Handler) http.Handler { return transport.InfobipFormHandler(h) },
+	Encode: func(c WireCall) *http.Request {
+		return formRequest(url.Values{
+			"SESSION_ID":   {c.SessionID},
+			"MSISDN":       {c.Msisdn},
+			"SERVICE_CODE": {c.ServiceCode},
+			"USSD_STRING":  {c.Text},
+		})
+	},
+	Decode: decodePrefixed,
+}
+
+// Vodacom posts JSON and reads {"type":"Response","text":"CON ..."}.
+var Vodacom = Wire{
+	Name:  "vodacom",
+	Mount: func(h core.Handler) http.Handler { return transport.VodacomHandler(h) },
+	Encode: func(c WireCall) *http.Request {
+		return jsonRequest(map[string]any{
+			"sessionId":   c.SessionID,
+			"msisdn":      c.Msisdn,
+			"serviceCode": c.ServiceCode,
+			"userInput":   c.Text,
+		})
+	},
+	Decode: func(res *http.Response) (core.Reply, error) {
+		var out struct {
+			Text string `json:"text"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+			return core.Reply{}, err
+		}
+		return parsePrefixed(out.Text)
+	},
+}
+
+// MTN posts JSON with messageType 0 (begin) or 1 (continue); a reply with
+// messageType 2 ends the session.
+var MTN = Wire{
+	Name:  "mtn",
+	Mount: func(h core.Handler) http.Handler { return transport.MTNHandler(h) },
+	Encode: func(c WireCall) *http.Request {
+		typ, text := "1", c.Text
+		if c.First {
+			typ, text = "0", c.ServiceCode
+		}
+		return jsonRequest(map[string]any{
+			"sessionId":   c.SessionID,
+			"msisdn":      c.Msisdn,
+			"serviceCode": c.ServiceCode,
+			"messageType": typ,
+			"ussdString":  text,
+		})
+	},
+	Decode: func(res *http.Response) (core.Reply, error) {
+		var out struct {
+			Type string `json:"messageType"`
+			Text string `json:"ussdString"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+			return core.Reply{}, err
+		}
+		return core.Reply{Continue: out.Type != "2", Message: out.Text}, nil
+	},
+}
+
+// GenericHTTP posts the field names understood by transport.HTTPHandler.
+var GenericHTTP = Wire{
+	Name:  "http",
+	Mount: func(h core.Handler) http.Handler { return transport.HTTPHandler(h) },
+	Encode: func(c WireCall) *http.Request {
+		return formRequest(url.Values{
+			"sessionId":   {c.SessionID},
+			"phoneNumber": {c.Msisdn},
+			"serviceCode": {c.ServiceCode},
+			"text":        {c.Text},
+		})
+	},
+	Decode: decodePrefixed,
+}
+
+func formRequest(v url.Values) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/ussd", strings.NewReader(v.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+func jsonRequest(doc map[string]any) *http.Request {
+	b, _ := json.Marshal(doc)
+	r := httptest.NewRequest(http.MethodPost, "/ussd", strings.NewReader(string(b)))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+func decodePrefixed(res *http.Response) (core.Reply, error) {
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return core.Reply{}, err
+	}
+	return parsePrefixed(string(b))
+}
+
+// parsePrefixed splits a "CON ..."/"END ..." body.
+func parsePrefixed(s string) (core.Reply, error) {
+	switch {
+	case strings.HasPrefix(s, "CON "):
+		return core.CON(s[4:]), nil
+	case strings.HasPrefix(s, "END "):
+		return core.END(s[4:]), nil
+	}
+	return core.Reply{}, fmt.Errorf("reply without CON/END prefix: %q", s)
+}
@@ -0,0 +1,56 @@
+package testkit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/router"
+	"github.com/grahms/cardinal/store"
+)
+
+func topUpEngine() *core.Engine {
+	r := router.New("/home")
+	r.SHOW("/home", func(c *router.Ctx) core.Reply { return core.CON("Bem-vindo\n1) Recarga\n2) Saldo") })
+	r.INPUT("/home", func(c *router.Ctx) core.Reply {
+		switch c.In() {
+		case "1":
+			c.Redirect("/amount")
+		case "2":
+			return core.END("Saldo: 250 MT")
+		}
+		return core.CON("")
+	})
+	r.SHOW("/amount", func(c *router.Ctx) core.Reply { return core.CON("Valor?") })
+	r.INPUT("/amount", func(c *router.Ctx) core.Reply {
+		c.Set("amount", c.In())
+		c.Redirect("/confirm")
+		return core.CON("")
+	})
+	r.SHOW("/confirm", func(c *router.Ctx) core.Reply {
+		return core.CON("Recarregar " + c.Session.MustString("amount") + " MT?\n1) Sim")
+	})
+	r.INPUT("/confirm", func(c *router.Ctx) core.Reply {
+		return core.END("Recarga de " + c.Session.MustString("amount") + " MT concluída")
+	})
+	return core.New(r.Mount(), core.Config{Store: store.NewInMemoryStore(time.Minute, store.WithoutGC())})
+}
+
+func TestRunWires(t *testing.T) {
+	sc := Scenario{
+		Msisdn:      "+258840000001",
+		ServiceCode: "*144#",
+		Steps: []Step{
+			{Expect: "Bem-vindo"},
+			{Input: "9", Expect: "Bem-vindo"}, // invalid choice repeats the menu
+			{Input: "1", Expect: "Valor?"},
+			{Input: "100", Match: `^Recarregar 100 MT\?`},
+			{Input: "1", Expect: "Recarga de 100 MT concluída", End: true},
+		},
+	}
+	wires := append([]Wire(nil), Wires...) // each vendor's own mode
+	for _, w := range Wires {
+		wires = append(wires, w.Accumulated(), w.LastToken())
+	}
+	RunWires(t, sc, topUpEngine, wires...)
+}
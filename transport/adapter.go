@@ -0,0 +1,336 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/grahms/cardinal/core"
+)
+
+// AdapterConfig describes a gateway's wire format so it can be onboarded
+// without Go code. Load it from JSON with LoadAdapterConfig (the yaml tags let
+// you use your YAML library of choice) and build it with NewAdapter.
+//
+//	{
+//	  "vendor": "acme",
+//	  "input":  {"encoding": "json", "sessionId": "session.id", "msisdn": "from",
+//	             "text": "input", "kind": "event", "kinds": {"START": "begin", "CANCEL": "abort"}},
+//	  "output": {"format": "json", "text": "message", "signal": "field",
+//	             "signalField": "final", "continue": "false", "end": "true",
+//	             "static": {"version": 2}}
+//	}
+type AdapterConfig struct {
+	Vendor string        `json:"vendor" yaml:"vendor"`
+	Input  AdapterInput  `json:"input" yaml:"input"`
+	Output AdapterOutput `json:"output" yaml:"output"`
+
+	// MSISDNPolicy overrides the package default (SetMSISDNPolicy) for this adapter.
+	MSISDNPolicy *MSISDNPolicy `json:"msisdnPolicy,omitempty" yaml:"msisdnPolicy,omitempty"`
+	// TrustedProxies overrides the package default (SetTrustedProxies).
+	TrustedProxies []string `json:"trustedProxies,omitempty" yaml:"trustedProxies,omitempty"`
+}
+
+// AdapterInput locates request fields. Paths are keys for form/query, dotted
+// paths for JSON ("session.id") and element paths for XML (see XMLHandler).
+type AdapterInput struct {
+	Encoding    string            `json:"encoding" yaml:"encoding"` // form | query | json | xml
+	SessionID   string            `json:"sessionId" yaml:"sessionId"`
+	Msisdn      string            `json:"msisdn" yaml:"msisdn"`
+	Text        string            `json:"text" yaml:"text"`
+	ServiceCode string            `json:"serviceCode" yaml:"serviceCode"`
+	Kind        string            `json:"kind" yaml:"kind"`   // optional field carrying the request type
+	Kinds       map[string]string `json:"kinds" yaml:"kinds"` // raw value -> begin|continue|abort|timeout
+}
+
+// AdapterOutput describes the reply.
+//
+// Signal modes:
+//   - prefix: message is prefixed with Continue/End (default "CON "/"END ")
+//   - field:  JSON field SignalField is set to Continue/End ("true"/"false" become booleans)
+//   - header: HTTP header SignalField is set to Continue/End
+//   - status: HTTP status ContinueStatus/EndStatus
+type AdapterOutput struct {
+	Format         string            `json:"format" yaml:"format"` // text | json | xml
+	ContentType    string            `json:"contentType" yaml:"contentType"`
+	Text           string            `json:"text" yaml:"text"` // JSON dotted path of the message
+	Signal         string            `json:"signal" yaml:"signal"`
+	SignalField    string            `json:"signalField" yaml:"signalField"`
+	Continue       string            `json:"continue" yaml:"continue"`
+	End            string            `json:"end" yaml:"end"`
+	ContinueStatus int               `json:"continueStatus" yaml:"continueStatus"`
+	EndStatus      int               `json:"endStatus" yaml:"endStatus"`
+	Template       string            `json:"template" yaml:"template"` // xml format, same data as XMLHandler
+	Static         map[string]any    `json:"static" yaml:"static"`     // extra JSON fields
+	Headers        map[string]string `json:"headers" yaml:"headers"`   // extra response headers
+}
+
+// LoadAdapterConfig reads a JSON adapter description.
+func LoadAdapterConfig(path string) (AdapterConfig, error) {
+	var cfg AdapterConfig
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	err = json.Unmarshal(b, &cfg)
+	return cfg, err
+}
+
+// NewAdapter builds an http.Handler from cfg, validating it up front.
+func NewAdapter(eng core.Handler, cfg AdapterConfig) (http.Handler, error) {
+	in, out := cfg.Input, cfg.Output
+	policy := Policy{MSISDN: cfg.MSISDNPolicy}
+	if cfg.TrustedProxies != nil {
+		tp, err := ParseTrustedProxies(cfg.TrustedProxies...)
+		if err != nil {
+			return nil, fmt.Errorf("adapter %q: trustedProxies: %w", cfg.Vendor, err)
+		}
+		policy.Proxies = tp
+	}
+	switch in.Encoding {
+	case "form", "query", "json", "xml":
+	default:
+		return nil, fmt.Errorf("adapter %q: unknown input encoding %q", cfg.Vendor, in.Encoding)
+	}
+	if in.SessionID == "" {
+		return nil, fmt.Errorf("adapter %q: input.sessionId is required", cfg.Vendor)
+	}
+	kinds := map[string]core.Kind{}
+	for raw, k := range in.Kinds {
+		kk, ok := core.ParseKind(k)
+		if !ok {
+			return nil, fmt.Errorf("adapter %q: unknown kind %q", cfg.Vendor, k)
+		}
+		kinds[raw] = kk
+	}
+
+	if out.Format == "" {
+		out.Format = "text"
+	}
+	if out.Signal == "" {
+		out.Signal = "prefix"
+	}
+	switch out.Signal {
+	case "prefix":
+		if out.Continue == "" && out.End == "" {
+			out.Continue, out.End = "CON ", "END "
+		}
+	case "field", "header":
+		if out.SignalField == "" {
+			return nil, fmt.Errorf("adapter %q: output.signalField is required for %s signalling", cfg.Vendor, out.Signal)
+		}
+		if out.Continue == "" || out.End == "" || out.Continue == out.End {
+			return nil, fmt.Errorf("adapter %q: output.continue and output.end must be set and differ for %s signalling", cfg.Vendor, out.Signal)
+		}
+	case "status":
+		if out.ContinueStatus == 0 || out.EndStatus == 0 || out.ContinueStatus == out.EndStatus {
+			return nil, fmt.Errorf("adapter %q: output.continueStatus and output.endStatus must be set and differ", cfg.Vendor)
+		}
+	default:
+		return nil, fmt.Errorf("adapter %q: unknown signal %q", cfg.Vendor, out.Signal)
+	}
+	var tmpl *template.Template
+	switch out.Format {
+	case "text":
+		if out.ContentType == "" {
+			out.ContentType = "text/plain; charset=utf-8"
+		}
+	case "json":
+		if out.Text == "" {
+			out.Text = "text"
+		}
+		if out.ContentType == "" {
+			out.ContentType = "application/json; charset=utf-8"
+		}
+	case "xml":
+		if out.Template == "" {
+			return nil, fmt.Errorf("adapter %q: output.template is required for xml", cfg.Vendor)
+		}
+		t, err := template.New(cfg.Vendor).Parse(out.Template)
+		if err != nil {
+			return nil, fmt.Errorf("adapter %q: %w", cfg.Vendor, err)
+		}
+		tmpl = t
+		if out.ContentType == "" {
+			out.ContentType = "text/xml; charset=utf-8"
+		}
+	default:
+		return nil, fmt.Errorf("adapter %q: unknown output format %q", cfg.Vendor, out.Format)
+	}
+	if out.Signal == "field" && out.Format != "json" {
+		return nil, fmt.Errorf("adapter %q: field signalling needs json output", cfg.Vendor)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		get, err := fieldReader(in.Encoding, r)
+		if err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		req := core.Request{
+			SessionID:   get(in.SessionID),
+			Msisdn:      strings.TrimSpace(get(in.Msisdn)),
+			ServiceCode: strings.TrimSpace(get(in.ServiceCode)),
+			Text:        strings.TrimSpace(get(in.Text)),
+			Meta: map[string]string{
+				"vendor": cfg.Vendor,
+				"ip":     policy.ClientIP(r),
+			},
+		}
+		if in.Kind != "" {
+			raw := get(in.Kind)
+			req.Meta["kind"] = raw
+			req.Kind = kinds[raw]
+		}
+		if err := policy.Normalize(&req); err != nil {
+			http.Error(w, "bad msisdn", http.StatusBadRequest)
+			return
+		}
+		rep, _ := eng.Handle(r.Context(), req)
+
+		flag := out.End
+		status := out.EndStatus
+		if rep.Continue {
+			flag, status = out.Continue, out.ContinueStatus
+		}
+		msg := rep.Message
+		if out.Signal == "prefix" {
+			msg = flag + msg
+		}
+
+		var body []byte
+		switch out.Format {
+		case "text":
+			body = []byte(msg)
+		case "json":
+			doc := cloneDoc(out.Static)
+			setPath(doc, out.Text, msg)
+			if out.Signal == "field" {
+				setPath(doc, out.SignalField, jsonScalar(flag))
+			}
+			body, _ = json.Marshal(doc)
+		case "xml":
+			var buf bytes.Buffer
+			err := tmpl.Execute(&buf, map[string]any{
+				"SessionID":   xmlEscape(req.SessionID),
+				"Msisdn":      xmlEscape(req.Msisdn),
+				"ServiceCode": xmlEscape(req.ServiceCode),
+				"Message":     xmlEscape(msg),
+				"Flag":        xmlEscape(flag),
+				"Continue":    rep.Continue,
+			})
+			if err != nil {
+				http.Error(w, "template error", http.StatusInternalServerError)
+				return
+			}
+			body = buf.Bytes()
+		}
+
+		for k, v := range out.Headers {
+			w.Header().Set(k, v)
+		}
+		if out.Signal == "header" {
+			w.Header().Set(out.SignalField, flag)
+		}
+		w.Header().Set("Content-Type", out.ContentType)
+		if out.Signal == "status" {
+			w.WriteHeader(status)
+		}
+		_, _ = w.Write(body)
+	}), nil
+}
+
+// fieldReader returns a lookup over the decoded request body.
+func fieldReader(enc string, r *http.Request) (func(string) string, error) {
+	switch enc {
+	case "form":
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		return r.FormValue, nil
+	case "query":
+		q := r.URL.Query()
+		return q.Get, nil
+	case "json":
+		var doc map[string]any
+		dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+		dec.UseNumber() // float64 would mangle numeric session IDs above 2^53
+		if err := dec.Decode(&doc); err != nil {
+			return nil, err
+		}
+		return func(p string) string { return jsonPath(doc, p) }, nil
+	case "xml":
+		doc, err := parseXML(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			return nil, err
+		}
+		return doc.find, nil
+	}
+	return nil, fmt.Errorf("unknown encoding %q", enc)
+}
+
+// jsonPath resolves a dotted path; numbers and booleans are stringified.
+func jsonPath(doc map[string]any, path string) string {
+	if path == "" {
+		return ""
+	}
+	var cur any = doc
+	for _, k := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return ""
+		}
+		cur = m[k]
+	}
+	switch v := cur.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+func setPath(doc map[string]any, path string, v any) {
+	keys := strings.Split(path, ".")
+	m := doc
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := m[k].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			m[k] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = v
+}
+
+// cloneDoc deep-copies nested maps so per-request writes never touch the config.
+func cloneDoc(m map[string]any) map[string]any {
+	cp := make(map[string]any, len(m))
+	for k, v := range m {
+		if sub, ok := v.(map[string]any); ok {
+			v = cloneDoc(sub)
+		}
+		cp[k] = v
+	}
+	return cp
+}
+
+func jsonScalar(s string) any {
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return s
+}
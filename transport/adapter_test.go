@@ -0,0 +1,179 @@
+package transport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/grahms/cardinal/core"
+)
+
+func TestAdapterEncodings(t *testing.T) {
+	kinds := map[string]string{"B": "begin", "X": "abort"}
+	tests := []struct {
+		name string
+		in   AdapterInput
+		req  func() *http.Request
+	}{
+		{"form", AdapterInput{Encoding: "form", SessionID: "sid", Msisdn: "from", Text: "input", ServiceCode: "code", Kind: "ev", Kinds: kinds},
+			func() *http.Request {
+				v := url.Values{"sid": {"s1"}, "from": {"+258840000001"}, "input": {"1"}, "code": {"*144#"}, "ev": {"X"}}
+				r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(v.Encode()))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return r
+			}},
+		{"query", AdapterInput{Encoding: "query", SessionID: "sid", Msisdn: "from", Text: "input", ServiceCode: "code", Kind: "ev", Kinds: kinds},
+			func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/?sid=s1&from=%2B258840000001&input=1&code=*144%23&ev=X", nil)
+			}},
+		{"json", AdapterInput{Encoding: "json", SessionID: "session.id", Msisdn: "session.from", Text: "input", ServiceCode: "code", Kind: "ev", Kinds: kinds},
+			func() *http.Request {
+				body := `{"session":{"id":"s1","from":"+258840000001"},"input":"1","code":"*144#","ev":"X"}`
+				return httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			}},
+		{"xml", AdapterInput{Encoding: "xml", SessionID: "req/sid", Msisdn: "req/from", Text: "req/input", ServiceCode: "req/code", Kind: "req/ev", Kinds: kinds},
+			func() *http.Request {
+				body := `<req><sid>s1</sid><from>+258840000001</from><input>1</input><code>*144#</code><ev>X</ev></req>`
+				return httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c capture
+			h, err := NewAdapter(&c, AdapterConfig{Vendor: "acme", Input: tt.in})
+			if err != nil {
+				t.Fatal(err)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), tt.req())
+			want := core.Request{SessionID: "s1", Msisdn: "+258840000001", Text: "1", ServiceCode: "*144#", Kind: core.KindAbort}
+			got := c.req
+			if got.SessionID != want.SessionID || got.Msisdn != want.Msisdn || got.Text != want.Text ||
+				got.ServiceCode != want.ServiceCode || got.Kind != want.Kind {
+				t.Fatalf("got %+v, want %+v", got, want)
+			}
+			if got.Meta["vendor"] != "acme" || got.Meta["kind"] != "X" {
+				t.Fatalf("Meta = %v", got.Meta)
+			}
+		})
+	}
+}
+
+// float64 decoding would round both IDs to 12345678901234567000.
+func TestAdapterJSONLargeNumbers(t *testing.T) {
+	var c capture
+	h, err := NewAdapter(&c, AdapterConfig{Input: AdapterInput{Encoding: "json", SessionID: "sid", Msisdn: "msisdn"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"12345678901234567891", "12345678901234567892"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"sid":`+id+`,"msisdn":258840000001}`)))
+		if c.req.SessionID != id {
+			t.Fatalf("SessionID = %q, want %q", c.req.SessionID, id)
+		}
+		if c.req.Msisdn != "258840000001" {
+			t.Fatalf("Msisdn = %q", c.req.Msisdn)
+		}
+	}
+}
+
+func TestAdapterSignals(t *testing.T) {
+	in := AdapterInput{Encoding: "query", SessionID: "sid"}
+	tests := []struct {
+		name  string
+		out   AdapterOutput
+		check func(t *testing.T, rep core.Reply, w *httptest.ResponseRecorder)
+	}{
+		{"prefix", AdapterOutput{}, func(t *testing.T, rep core.Reply, w *httptest.ResponseRecorder) {
+			want := "END Bye"
+			if rep.Continue {
+				want = "CON Menu"
+			}
+			if w.Body.String() != want {
+				t.Fatalf("body = %q, want %q", w.Body.String(), want)
+			}
+		}},
+		{"field", AdapterOutput{Format: "json", Text: "msg.text", Signal: "field", SignalField: "final", Continue: "false", End: "true", Static: map[string]any{"v": 2}},
+			func(t *testing.T, rep core.Reply, w *httptest.ResponseRecorder) {
+				var doc struct {
+					Msg   struct{ Text string }
+					Final bool
+					V     int
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+					t.Fatal(err)
+				}
+				if doc.Final == rep.Continue || doc.Msg.Text != rep.Message || doc.V != 2 {
+					t.Fatalf("body = %s", w.Body)
+				}
+			}},
+		{"header", AdapterOutput{Signal: "header", SignalField: "X-Session", Continue: "open", End: "close"},
+			func(t *testing.T, rep core.Reply, w *httptest.ResponseRecorder) {
+				want := "close"
+				if rep.Continue {
+					want = "open"
+				}
+				if got := w.Header().Get("X-Session"); got != want {
+					t.Fatalf("X-Session = %q, want %q", got, want)
+				}
+				if w.Body.String() != rep.Message {
+					t.Fatalf("body = %q", w.Body.String())
+				}
+			}},
+		{"status", AdapterOutput{Signal: "status", ContinueStatus: http.StatusOK, EndStatus: http.StatusAccepted},
+			func(t *testing.T, rep core.Reply, w *httptest.ResponseRecorder) {
+				want := http.StatusAccepted
+				if rep.Continue {
+					want = http.StatusOK
+				}
+				if w.Code != want {
+					t.Fatalf("status = %d, want %d", w.Code, want)
+				}
+			}},
+	}
+	for _, tt := range tests {
+		for _, rep := range []core.Reply{core.CON("Menu"), core.END("Bye")} {
+			t.Run(tt.name, func(t *testing.T) {
+				h, err := NewAdapter(&capture{rep: rep}, AdapterConfig{Input: in, Output: tt.out})
+				if err != nil {
+					t.Fatal(err)
+				}
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?sid=s1", nil))
+				tt.check(t, rep, w)
+			})
+		}
+	}
+}
+
+func TestAdapterConfigErrors(t *testing.T) {
+	in := AdapterInput{Encoding: "form", SessionID: "sid"}
+	tests := []struct {
+		name string
+		cfg  AdapterConfig
+	}{
+		{"encoding", AdapterConfig{Input: AdapterInput{Encoding: "csv", SessionID: "sid"}}},
+		{"session id", AdapterConfig{Input: AdapterInput{Encoding: "form"}}},
+		{"kind", AdapterConfig{Input: AdapterInput{Encoding: "form", SessionID: "sid", Kinds: map[string]string{"1": "start"}}}},
+		{"signal", AdapterConfig{Input: in, Output: AdapterOutput{Signal: "smoke"}}},
+		{"field without name", AdapterConfig{Input: in, Output: AdapterOutput{Format: "json", Signal: "field", Continue: "c", End: "e"}}},
+		{"field without values", AdapterConfig{Input: in, Output: AdapterOutput{Format: "json", Signal: "field", SignalField: "f"}}},
+		{"field same values", AdapterConfig{Input: in, Output: AdapterOutput{Format: "json", Signal: "field", SignalField: "f", Continue: "x", End: "x"}}},
+		{"header without end", AdapterConfig{Input: in, Output: AdapterOutput{Signal: "header", SignalField: "X-S", Continue: "c"}}},
+		{"field on text", AdapterConfig{Input: in, Output: AdapterOutput{Signal: "field", SignalField: "f", Continue: "c", End: "e"}}},
+		{"status missing", AdapterConfig{Input: in, Output: AdapterOutput{Signal: "status", ContinueStatus: 200}}},
+		{"status same", AdapterConfig{Input: in, Output: AdapterOutput{Signal: "status", ContinueStatus: 200, EndStatus: 200}}},
+		{"xml without template", AdapterConfig{Input: in, Output: AdapterOutput{Format: "xml"}}},
+		{"format", AdapterConfig{Input: in, Output: AdapterOutput{Format: "yaml"}}},
+		{"proxies", AdapterConfig{Input: in, TrustedProxies: []string{"not-a-cidr"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewAdapter(&capture{}, tt.cfg); err == nil {
+				t.Fatal("want error")
+			}
+		})
+	}
+}
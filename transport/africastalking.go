@@ -11,14 +11,20 @@ import (
 // AfricaTalkingHandler handles AT's form-encoded USSD POSTs.
 // Docs (common shape):
 //   - sessionId: string
-//   - serviceCode: string (ignored here)
+//   - serviceCode: dialled code, e.g. "*144#" (used by dispatch.Dispatcher)
 //   - phoneNumber: +<cc><msisdn>
 //   - text: accumulated input "1*100*1" or last token (we forward as-is; engine uses last token)
-func AfricaTalkingHandler(eng *core.Engine, opts ...ATOption) http.Handler {
+//
+// Empty text marks a new session (core.KindBegin). End-of-session notifications
+// (a "status" field) with a status other than "Success" are forwarded as
+// core.KindAbort so the app can clean up; Meta["status"] keeps the raw value.
+func AfricaTalkingHandler(eng core.Handler, opts ...ATOption) http.Handler {
 	cfg := atConfig{
-		FieldSessionID: "sessionId",
-		FieldMsisdn:    "phoneNumber",
-		FieldText:      "text",
+		FieldSessionID:   "sessionId",
+		FieldMsisdn:      "phoneNumber",
+		FieldServiceCode: "serviceCode",
+		FieldText:        "text",
+		FieldStatus:      "status",
 	}
 	for _, o := range opts {
 		o(&cfg)
@@ -30,14 +36,32 @@ func AfricaTalkingHandler(eng *core.Engine, opts ...ATOption) http.Handler {
 			return
 		}
 		req := core.Request{
-			SessionID: r.FormValue(cfg.FieldSessionID),
-			Msisdn:    strings.TrimSpace(r.FormValue(cfg.FieldMsisdn)),
-			Text:      strings.TrimSpace(r.FormValue(cfg.FieldText)),
+			SessionID:   r.FormValue(cfg.FieldSessionID),
+			Msisdn:      strings.TrimSpace(r.FormValue(cfg.FieldMsisdn)),
+			ServiceCode: strings.TrimSpace(r.FormValue(cfg.FieldServiceCode)),
+			Text:        strings.TrimSpace(r.FormValue(cfg.FieldText)),
 			Meta: map[string]string{
 				"vendor": "africastalking",
-				"ip":     clientIP(r),
+				"ip":     cfg.Policy.ClientIP(r),
 			},
 		}
+		switch status := r.FormValue(cfg.FieldStatus); {
+		case status == "Success":
+			// notification for a session that already ended normally
+			w.WriteHeader(http.StatusOK)
+			return
+		case status != "":
+			req.Kind = core.KindAbort
+			req.Meta["status"] = status
+		case req.Text == "":
+			req.Kind = core.KindBegin
+		default:
+			req.Kind = core.KindContinue
+		}
+		if err := cfg.Policy.Normalize(&req); err != nil {
+			http.Error(w, "bad msisdn", http.StatusBadRequest)
+			return
+		}
 		rep, _ := eng.Handle(r.Context(), req)
 		prefix := "CON "
 		if !rep.Continue {
@@ -50,14 +74,26 @@ func AfricaTalkingHandler(eng *core.Engine, opts ...ATOption) http.Handler {
 }
 
 type atConfig struct {
-	FieldSessionID string
-	FieldMsisdn    string
-	FieldText      string
+	FieldSessionID   string
+	FieldMsisdn      string
+	FieldServiceCode string
+	FieldText        string
+	FieldStatus      string // end-of-session notification status
+
+	Policy Policy
 }
 type ATOption func(*atConfig)
 
-func ATFields(sessionID, msisdn, text string) ATOption {
+// ATPolicy sets this handler's request policy (default: the package defaults).
+func ATPolicy(p Policy) ATOption { return func(c *atConfig) { c.Policy = p } }
+
+// ATFields overrides the form field names. Empty values keep the default;
+// an optional fourth name overrides serviceCode.
+func ATFields(sessionID, msisdn, text string, serviceCode ...string) ATOption {
 	return func(c *atConfig) {
+		if len(serviceCode) > 0 && serviceCode[0] != "" {
+			c.FieldServiceCode = serviceCode[0]
+		}
 		if sessionID != "" {
 			c.FieldSessionID = sessionID
 		}
@@ -0,0 +1,386 @@
+// Package async is a callback-mode USSD transport for aggregators that expect
+// an immediate 200 acknowledgement and the reply POSTed to a callback URL.
+//
+// Inbound requests are acknowledged and queued; workers run them through the
+// engine and queue the reply for delivery. Separate delivery workers POST it
+// with an outbound HTTP client, retrying with exponential backoff and signing
+// each body with HMAC-SHA256, so a slow callback never holds up engine steps.
+//
+//	tr := async.New(eng, async.Config{CallbackURL: "https://agg.example/ussd/reply", Secret: "s3cr3t"})
+//	tr.Start(ctx)
+//	defer tr.Close()
+//	mux.Handle("/ussd/async", tr.Handler())
+package async
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/transport"
+)
+
+// ErrDeliveryQueueFull is passed to OnFailure when a reply could not be queued.
+var ErrDeliveryQueueFull = errors.New("async: delivery queue full")
+
+// ErrClosed is passed to OnFailure for work still queued when Close is called.
+var ErrClosed = errors.New("async: transport closed")
+
+// Delivery is one outbound reply.
+type Delivery struct {
+	URL         string
+	Request     core.Request
+	Reply       core.Reply
+	Body        []byte
+	ContentType string
+	Attempts    int
+}
+
+// Config for the async transport.
+type Config struct {
+	// CallbackURL receives every reply unless CallbackFor returns a URL.
+	CallbackURL string
+	// CallbackFor picks a per-request callback (e.g. from a field the gateway
+	// sent). Default: none. The inbound callbackUrl is attacker-controlled, so
+	// opt in with CallbackFrom and an allow-list of gateway hosts.
+	CallbackFor func(core.Request) string
+
+	// Decode turns the inbound HTTP request into a core.Request.
+	// Default: form/query keys sessionId, msisdn|phoneNumber, serviceCode, text,
+	// with callbackUrl kept in Meta["callbackUrl"].
+	Decode func(*http.Request) (core.Request, error)
+	// Encode renders the outbound body and its content type.
+	// Default: JSON {sessionId, msisdn, continue, message}.
+	Encode func(core.Request, core.Reply) ([]byte, string)
+	// MSISDN normalizes decoded numbers (default: transport.SetMSISDNPolicy).
+	MSISDN *transport.MSISDNPolicy
+
+	Secret          string // HMAC-SHA256 key for the signature header (optional)
+	SignatureHeader string // default "X-Cardinal-Signature"
+
+	Client     *http.Client  // default: 10s timeout
+	MaxRetries int           // attempts after the first (default 5)
+	Backoff    time.Duration // first retry delay, doubled each time (default 500ms)
+	QueueSize  int           // pending inbound steps (default 1024)
+	Workers    int           // concurrent engine steps (default 4)
+
+	DeliveryQueueSize int // pending outbound replies (default 1024)
+	DeliveryWorkers   int // concurrent deliveries, including retry waits (default 4)
+
+	// OnFailure is called when a delivery is abandoned after all retries, with
+	// ErrDeliveryQueueFull when it could not be queued, and with ErrClosed for
+	// steps and replies still queued at Close (steps carry no Reply or URL yet).
+	OnFailure func(Delivery, error)
+	Logger    *log.Logger // default log.Default()
+}
+
+// Transport acknowledges inbound steps and delivers replies asynchronously.
+type Transport struct {
+	cfg        Config
+	eng        core.Handler
+	queue      chan core.Request
+	deliveries chan Delivery
+
+	mu     sync.RWMutex
+	closed bool
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func New(eng core.Handler, cfg Config) *Transport {
+	if cfg.Decode == nil {
+		cfg.Decode = DecodeForm
+	}
+	if cfg.Encode == nil {
+		cfg.Encode = EncodeJSON
+	}
+	if cfg.SignatureHeader == "" {
+		cfg.SignatureHeader = "X-Cardinal-Signature"
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 5
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = 500 * time.Millisecond
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1024
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+	if cfg.DeliveryQueueSize <= 0 {
+		cfg.DeliveryQueueSize = 1024
+	}
+	if cfg.DeliveryWorkers <= 0 {
+		cfg.DeliveryWorkers = 4
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.Default()
+	}
+	return &Transport{
+		cfg:        cfg,
+		eng:        eng,
+		queue:      make(chan core.Request, cfg.QueueSize),
+		deliveries: make(chan Delivery, cfg.DeliveryQueueSize),
+	}
+}
+
+// Start launches the workers. They stop when ctx is done or Close is called.
+func (t *Transport) Start(ctx context.Context) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ctx, t.cancel = context.WithCancel(ctx)
+	for i := 0; i < t.cfg.Workers; i++ {
+		t.wg.Add(1)
+		go t.work(ctx)
+	}
+	for i := 0; i < t.cfg.DeliveryWorkers; i++ {
+		t.wg.Add(1)
+		go t.send(ctx)
+	}
+}
+
+// Close stops accepting steps, stops the workers and waits for in-flight
+// deliveries to return. Steps and replies still queued are reported to
+// OnFailure with ErrClosed.
+func (t *Transport) Close() {
+	t.mu.Lock()
+	t.closed = true
+	if t.cancel != nil {
+		t.cancel()
+	}
+	t.mu.Unlock()
+	t.wg.Wait()
+	for {
+		select {
+		case req := <-t.queue:
+			t.fail(Delivery{Request: req}, ErrClosed)
+		case d := <-t.deliveries:
+			t.fail(d, ErrClosed)
+		default:
+			return
+		}
+	}
+}
+
+// Handler acknowledges with 200 once the step is queued (503 if the queue is full).
+func (t *Transport) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := t.cfg.Decode(r)
+		if err == nil {
+			err = (transport.Policy{MSISDN: t.cfg.MSISDN}).Normalize(&req)
+		}
+		if err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		t.mu.RLock()
+		defer t.mu.RUnlock()
+		if t.closed {
+			http.Error(w, "closed", http.StatusServiceUnavailable)
+			return
+		}
+		select {
+		case t.queue <- req:
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("OK"))
+		default:
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+		}
+	})
+}
+
+func (t *Transport) work(ctx context.Context) {
+	defer t.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case req := <-t.queue:
+			t.process(ctx, req)
+		}
+	}
+}
+
+func (t *Transport) process(ctx context.Context, req core.Request) {
+	rep, _ := t.eng.Handle(ctx, req)
+	if req.Kind == core.KindAbort || req.Kind == core.KindTimeout {
+		return // nobody is waiting for a screen
+	}
+	url := t.cfg.CallbackURL
+	if t.cfg.CallbackFor != nil {
+		if u := t.cfg.CallbackFor(req); u != "" {
+			url = u
+		}
+	}
+	if url == "" {
+		t.cfg.Logger.Printf("async: no callback url for sid=%s", req.SessionID)
+		return
+	}
+	body, ct := t.cfg.Encode(req, rep)
+	d := Delivery{URL: url, Request: req, Reply: rep, Body: body, ContentType: ct}
+	select {
+	case t.deliveries <- d:
+	default:
+		t.fail(d, ErrDeliveryQueueFull)
+	}
+}
+
+func (t *Transport) send(ctx context.Context) {
+	defer t.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-t.deliveries:
+			if err := t.deliver(ctx, &d); err != nil {
+				t.fail(d, err)
+			}
+		}
+	}
+}
+
+func (t *Transport) fail(d Delivery, err error) {
+	t.cfg.Logger.Printf("async: delivery to %s sid=%s failed after %d attempts: %v", d.URL, d.Request.SessionID, d.Attempts, err)
+	if t.cfg.OnFailure != nil {
+		t.cfg.OnFailure(d, err)
+	}
+}
+
+func (t *Transport) deliver(ctx context.Context, d *Delivery) error {
+	wait := t.cfg.Backoff
+	var err error
+	for d.Attempts = 1; ; d.Attempts++ {
+		if err = t.post(ctx, d); err == nil {
+			return nil
+		}
+		if d.Attempts > t.cfg.MaxRetries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+func (t *Transport) post(ctx context.Context, d *Delivery) error {
+	hr, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Body))
+	if err != nil {
+		return err
+	}
+	hr.Header.Set("Content-Type", d.ContentType)
+	if t.cfg.Secret != "" {
+		hr.Header.Set(t.cfg.SignatureHeader, Sign(t.cfg.Secret, d.Body))
+	}
+	res, err := t.cfg.Client.Do(hr)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("callback status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// CallbackFrom returns a Config.CallbackFor that uses the callbackUrl the
+// gateway sent (Meta["callbackUrl"]) when it is http(s) and its host is one of
+// hosts. Other values fall back to CallbackURL.
+//
+//	async.Config{CallbackFor: async.CallbackFrom("agg.example", "agg2.example:8443")}
+func CallbackFrom(hosts ...string) func(core.Request) string {
+	return func(r core.Request) string {
+		u, err := neturl.Parse(r.Meta["callbackUrl"])
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.User != nil {
+			return ""
+		}
+		for _, h := range hosts {
+			if strings.EqualFold(u.Host, h) {
+				return u.String()
+			}
+		}
+		return ""
+	}
+}
+
+// Sign returns the hex HMAC-SHA256 of body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature produced by Sign in constant time.
+func Verify(secret string, body []byte, sig string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(sig))
+}
+
+// DecodeForm reads form/query keys: sessionId, msisdn|phoneNumber, serviceCode, text, callbackUrl.
+func DecodeForm(r *http.Request) (core.Request, error) {
+	if err := r.ParseForm(); err != nil {
+		return core.Request{}, err
+	}
+	req := core.Request{
+		SessionID:   r.FormValue("sessionId"),
+		Msisdn:      strings.TrimSpace(first(r.FormValue("msisdn"), r.FormValue("phoneNumber"))),
+		ServiceCode: r.FormValue("serviceCode"),
+		Text:        strings.TrimSpace(r.FormValue("text")),
+		Meta:        map[string]string{"vendor": "async"},
+	}
+	if cb := r.FormValue("callbackUrl"); cb != "" {
+		req.Meta["callbackUrl"] = cb
+	}
+	if req.SessionID == "" {
+		return req, errors.New("missing sessionId")
+	}
+	return req, nil
+}
+
+// EncodeJSON renders {sessionId, msisdn, continue, message}.
+func EncodeJSON(req core.Request, rep core.Reply) ([]byte, string) {
+	b, _ := json.Marshal(Callback{
+		SessionID: req.SessionID,
+		Msisdn:    req.Msisdn,
+		Continue:  rep.Continue,
+		Message:   rep.Message,
+	})
+	return b, "application/json; charset=utf-8"
+}
+
+// Callback is the default outbound JSON body.
+type Callback struct {
+	SessionID string `json:"sessionId"`
+	Msisdn    string `json:"msisdn"`
+	Continue  bool   `json:"continue"`
+	Message   string `json:"message"`
+}
+
+func first(vs ...string) string {
+	for _, v := range vs {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
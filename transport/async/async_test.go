@@ -0,0 +1,191 @@
+package async
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+)
+
+// engineFunc adapts a func to core.Handler.
+type engineFunc func(context.Context, core.Request) (core.Reply, error)
+
+func (f engineFunc) Handle(ctx context.Context, req core.Request) (core.Reply, error) {
+	return f(ctx, req)
+}
+
+func post(t *testing.T, h http.Handler, sid string) {
+	t.Helper()
+	v := url.Values{"sessionId": {sid}, "msisdn": {"+258840000001"}}
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(v.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+}
+
+func TestRetriesDoNotBlockEngine(t *testing.T) {
+	gw := NewStubGateway()
+	defer gw.Close()
+	gw.FailFirst = 1 << 30 // every callback fails and waits out its backoff
+
+	handled := make(chan string, 2)
+	eng := engineFunc(func(_ context.Context, req core.Request) (core.Reply, error) {
+		handled <- req.SessionID
+		return core.CON("Welcome"), nil
+	})
+	tr := New(eng, Config{CallbackURL: gw.URL(), Workers: 1, DeliveryWorkers: 1, Backoff: time.Hour})
+	tr.Start(context.Background())
+	defer tr.Close()
+
+	h := tr.Handler()
+	post(t, h, "s1")
+	post(t, h, "s2")
+	for _, want := range []string{"s1", "s2"} {
+		select {
+		case got := <-handled:
+			if got != want {
+				t.Fatalf("handled %q, want %q", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("engine step for %s blocked behind a delivery retry", want)
+		}
+	}
+}
+
+func TestDeliveryQueueFull(t *testing.T) {
+	gw := NewStubGateway()
+	defer gw.Close()
+	gw.FailFirst = 1 << 30
+
+	failed := make(chan error, 4)
+	eng := engineFunc(func(context.Context, core.Request) (core.Reply, error) { return core.CON("Welcome"), nil })
+	tr := New(eng, Config{
+		CallbackURL: gw.URL(), Workers: 1, DeliveryWorkers: 1, DeliveryQueueSize: 1, Backoff: time.Hour,
+		OnFailure: func(_ Delivery, err error) { failed <- err },
+	})
+	tr.Start(context.Background())
+	defer tr.Close()
+
+	h := tr.Handler()
+	post(t, h, "s1") // in flight, waiting to retry
+	for gw.Hits() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	post(t, h, "s2") // queued
+	post(t, h, "s3") // dropped
+	select {
+	case err := <-failed:
+		if err != ErrDeliveryQueueFull {
+			t.Fatalf("OnFailure err = %v, want ErrDeliveryQueueFull", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnFailure not called for a full delivery queue")
+	}
+}
+
+func TestDelivery(t *testing.T) {
+	gw := NewStubGateway()
+	defer gw.Close()
+	gw.FailFirst = 1
+
+	eng := engineFunc(func(context.Context, core.Request) (core.Reply, error) { return core.END("Bye"), nil })
+	tr := New(eng, Config{CallbackURL: gw.URL(), Secret: "s3cr3t", Backoff: time.Millisecond})
+	tr.Start(context.Background())
+	defer tr.Close()
+
+	post(t, tr.Handler(), "s1")
+	select {
+	case c := <-gw.Received():
+		if !Verify("s3cr3t", c.Body, c.Signature) {
+			t.Fatal("bad signature")
+		}
+		if !strings.Contains(string(c.Body), `"message":"Bye"`) {
+			t.Fatalf("body = %s", c.Body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no callback delivered")
+	}
+	if gw.Hits() != 2 {
+		t.Fatalf("hits = %d, want 2 (one retry)", gw.Hits())
+	}
+}
+
+func TestCallbackURLIgnoredByDefault(t *testing.T) {
+	gw := NewStubGateway()
+	defer gw.Close()
+	evil := NewStubGateway()
+	defer evil.Close()
+
+	eng := engineFunc(func(context.Context, core.Request) (core.Reply, error) { return core.END("Bye"), nil })
+	tr := New(eng, Config{CallbackURL: gw.URL(), Secret: "s3cr3t"})
+	tr.Start(context.Background())
+	defer tr.Close()
+
+	v := url.Values{"sessionId": {"s1"}, "msisdn": {"+258840000001"}, "callbackUrl": {evil.URL()}}
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(v.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	tr.Handler().ServeHTTP(httptest.NewRecorder(), r)
+
+	select {
+	case <-gw.Received():
+	case <-time.After(5 * time.Second):
+		t.Fatal("no callback to the configured URL")
+	}
+	if evil.Hits() != 0 {
+		t.Fatal("callback sent to the URL named in the request")
+	}
+}
+
+func TestCallbackFrom(t *testing.T) {
+	pick := CallbackFrom("agg.example", "agg2.example:8443")
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://agg.example/reply", "https://agg.example/reply"},
+		{"http://AGG.example/reply", "http://AGG.example/reply"},
+		{"https://agg2.example:8443/r", "https://agg2.example:8443/r"},
+		{"https://agg2.example/r", ""},
+		{"https://agg.example.evil.com/reply", ""},
+		{"https://user@agg.example/reply", ""},
+		{"ftp://agg.example/reply", ""},
+		{"http://169.254.169.254/latest", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		req := core.Request{Meta: map[string]string{"callbackUrl": tt.url}}
+		if got := pick(req); got != tt.want {
+			t.Errorf("CallbackFrom(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestCloseReportsQueued(t *testing.T) {
+	var failed []error
+	eng := engineFunc(func(context.Context, core.Request) (core.Reply, error) { return core.CON("Welcome"), nil })
+	tr := New(eng, Config{CallbackURL: "http://127.0.0.1:1", OnFailure: func(_ Delivery, err error) { failed = append(failed, err) }})
+	h := tr.Handler()
+	post(t, h, "s1") // accepted before Start: nobody has run it yet
+	post(t, h, "s2")
+	tr.Close()
+
+	if len(failed) != 2 || failed[0] != ErrClosed || failed[1] != ErrClosed {
+		t.Fatalf("OnFailure errors = %v, want two ErrClosed", failed)
+	}
+	v := url.Values{"sessionId": {"s3"}}
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(v.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status after Close = %d, want 503", w.Code)
+	}
+}
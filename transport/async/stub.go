@@ -0,0 +1,65 @@
+package async
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// Call is a callback captured by StubGateway.
+type Call struct {
+	Body      []byte
+	Header    http.Header
+	Signature string
+}
+
+// StubGateway is a local aggregator stand-in that records callbacks.
+// Set FailFirst before use to make the first N callbacks answer 500 (retries).
+//
+//	gw := async.NewStubGateway()
+//	defer gw.Close()
+//	tr := async.New(eng, async.Config{CallbackURL: gw.URL()})
+type StubGateway struct {
+	srv *httptest.Server
+	out chan Call
+
+	mu        sync.Mutex
+	FailFirst int
+	hits      int
+}
+
+func NewStubGateway() *StubGateway {
+	g := &StubGateway{out: make(chan Call, 64)}
+	g.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		g.mu.Lock()
+		g.hits++
+		fail := g.hits <= g.FailFirst
+		g.mu.Unlock()
+		if fail {
+			http.Error(w, "stub failure", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		g.out <- Call{Body: body, Header: r.Header.Clone(), Signature: r.Header.Get("X-Cardinal-Signature")}
+	}))
+	return g
+}
+
+func (g *StubGateway) URL() string { return g.srv.URL }
+
+// Received delivers every successfully acknowledged callback.
+func (g *StubGateway) Received() <-chan Call { return g.out }
+
+// Hits counts callback attempts, including failed ones.
+func (g *StubGateway) Hits() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.hits
+}
+
+func (g *StubGateway) Close() { g.srv.Close() }
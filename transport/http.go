@@ -9,7 +9,11 @@ import (
 
 // HTTPHandler returns a generic handler that understands common aggregator keys.
 // Accepted keys: sessionId, phoneNumber, serviceCode, text (case/alias tolerant)
-func HTTPHandler(e *core.Engine) http.Handler {
+func HTTPHandler(e core.Handler, opts ...HTTPOption) http.Handler {
+	var cfg httpConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_ = r.ParseForm()
 		f := func(k string) string { return pick(r.Form, k) }
@@ -20,6 +24,10 @@ func HTTPHandler(e *core.Engine) http.Handler {
 			Text:        f("text"),
 			Meta:        map[string]string{},
 		}
+		if err := cfg.Policy.Normalize(&req); err != nil {
+			http.Error(w, "bad msisdn", http.StatusBadRequest)
+			return
+		}
 		reply, _ := e.Handle(r.Context(), req)
 		prefix := "CON "
 		if !reply.Continue {
@@ -29,6 +37,14 @@ func HTTPHandler(e *core.Engine) http.Handler {
 	})
 }
 
+type httpConfig struct {
+	Policy Policy
+}
+type HTTPOption func(*httpConfig)
+
+// HTTPPolicy sets this handler's request policy (default: the package defaults).
+func HTTPPolicy(p Policy) HTTPOption { return func(c *httpConfig) { c.Policy = p } }
+
 func pick(v url.Values, key string) string {
 	if x := v.Get(key); x != "" {
 		return x
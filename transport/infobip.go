@@ -13,17 +13,19 @@ import (
 //
 //	SESSION_ID   -> core.Request.SessionID
 //	MSISDN       -> core.Request.Msisdn
+//	SERVICE_CODE -> core.Request.ServiceCode
 //	USSD_STRING  -> core.Request.Text         (primary, per docs)
 //
 // Fallbacks accepted for Text if USSD_STRING is empty: INPUT, text
 //
 // Response is plain text prefixed with "CON " or "END " as required by Infobip.
-func InfobipFormHandler(eng *core.Engine, opts ...IBOption) http.Handler {
+func InfobipFormHandler(eng core.Handler, opts ...IBOption) http.Handler {
 	cfg := ibConfig{
-		FieldSessionID: "SESSION_ID",
-		FieldMsisdn:    "MSISDN",
-		FieldText:      "USSD_STRING",
-		TextFallbacks:  []string{"INPUT", "text"},
+		FieldSessionID:   "SESSION_ID",
+		FieldMsisdn:      "MSISDN",
+		FieldServiceCode: "SERVICE_CODE",
+		FieldText:        "USSD_STRING",
+		TextFallbacks:    []string{"INPUT", "text"},
 	}
 	for _, o := range opts {
 		o(&cfg)
@@ -47,15 +49,20 @@ func InfobipFormHandler(eng *core.Engine, opts ...IBOption) http.Handler {
 		}
 
 		req := core.Request{
-			SessionID: r.FormValue(cfg.FieldSessionID),
-			Msisdn:    strings.TrimSpace(r.FormValue(cfg.FieldMsisdn)),
-			Text:      txt,
+			SessionID:   r.FormValue(cfg.FieldSessionID),
+			Msisdn:      strings.TrimSpace(r.FormValue(cfg.FieldMsisdn)),
+			ServiceCode: strings.TrimSpace(r.FormValue(cfg.FieldServiceCode)),
+			Text:        txt,
 			Meta: map[string]string{
 				"vendor": "infobip",
-				"ip":     clientIP(r),
+				"ip":     cfg.Policy.ClientIP(r),
 			},
 		}
 
+		if err := cfg.Policy.Normalize(&req); err != nil {
+			http.Error(w, "bad msisdn", http.StatusBadRequest)
+			return
+		}
 		rep, _ := eng.Handle(r.Context(), req)
 
 		prefix := "CON "
@@ -68,18 +75,28 @@ func InfobipFormHandler(eng *core.Engine, opts ...IBOption) http.Handler {
 }
 
 type ibConfig struct {
-	FieldSessionID string
-	FieldMsisdn    string
-	FieldText      string   // primary key for user input (default: USSD_STRING)
-	TextFallbacks  []string // additional keys to try if primary is empty
+	FieldSessionID   string
+	FieldMsisdn      string
+	FieldServiceCode string
+	FieldText        string   // primary key for user input (default: USSD_STRING)
+	TextFallbacks    []string // additional keys to try if primary is empty
+
+	Policy Policy
 }
 
 type IBOption func(*ibConfig)
 
+// IBPolicy sets this handler's request policy (default: the package defaults).
+func IBPolicy(p Policy) IBOption { return func(c *ibConfig) { c.Policy = p } }
+
 // IBFields overrides the default form field names.
 // Example: IBFields("SESSION", "MSISDN", "USSD") or IBFields("", "", "INPUT") to change only text.
-func IBFields(sessionID, msisdn, text string) IBOption {
+// An optional fourth name overrides SERVICE_CODE.
+func IBFields(sessionID, msisdn, text string, serviceCode ...string) IBOption {
 	return func(c *ibConfig) {
+		if len(serviceCode) > 0 && serviceCode[0] != "" {
+			c.FieldServiceCode = serviceCode[0]
+		}
 		if sessionID != "" {
 			c.FieldSessionID = sessionID
 		}
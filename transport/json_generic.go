@@ -15,7 +15,7 @@ import (
 //	    JSONMap{InSessionID: "sid", InMsisdn: "from", InText: "payload"},
 //	    JSONMap{OutTextKey: "msg", OutWrapperKey: "kind", OutWrapperVal: "Response"},
 //	))
-func JSONGenericHandler(eng *core.Engine, in JSONMap, out JSONMap) http.Handler {
+func JSONGenericHandler(eng core.Handler, in JSONMap, out JSONMap) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var body map[string]any
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -28,9 +28,13 @@ func JSONGenericHandler(eng *core.Engine, in JSONMap, out JSONMap) http.Handler
 			Text:      strings.TrimSpace(asString(body[in.InText])),
 			Meta: map[string]string{
 				"vendor": "generic-json",
-				"ip":     clientIP(r),
+				"ip":     in.Policy.ClientIP(r),
 			},
 		}
+		if err := in.Policy.Normalize(&req); err != nil {
+			http.Error(w, "bad msisdn", http.StatusBadRequest)
+			return
+		}
 		rep, _ := eng.Handle(r.Context(), req)
 
 		outDoc := map[string]any{}
@@ -54,4 +58,6 @@ type JSONMap struct {
 	OutTextKey    string
 	OutWrapperKey string
 	OutWrapperVal string
+
+	Policy Policy // read from the inbound map
 }
@@ -0,0 +1,103 @@
+package transport
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+
+	"github.com/grahms/cardinal/core"
+)
+
+// ErrInvalidMSISDN is returned by MSISDNPolicy.Normalize for numbers that cannot be E.164.
+var ErrInvalidMSISDN = errors.New("invalid msisdn")
+
+// MSISDNPolicy turns the many shapes gateways send ("+25884...", "25884...",
+// "084...", "84...") into one E.164 form, so the same subscriber has the same
+// MSISDN in sessions, rate limits and lookups.
+type MSISDNPolicy struct {
+	CountryCode    string `json:"countryCode" yaml:"countryCode"`       // default country code without "+", e.g. "258"
+	NationalPrefix string `json:"nationalPrefix" yaml:"nationalPrefix"` // trunk prefix dropped from national numbers, e.g. "0"
+	NationalLength int    `json:"nationalLength" yaml:"nationalLength"` // digits after the country code (e.g. 9); 0 skips length checks
+	Plus           bool   `json:"plus" yaml:"plus"`                     // emit "+25884..." instead of "25884..."
+	Strict         bool   `json:"strict" yaml:"strict"`                 // reject invalid numbers instead of passing them through
+}
+
+// Normalize returns msisdn in E.164 (with or without "+", per Plus).
+func (p MSISDNPolicy) Normalize(msisdn string) (string, error) {
+	d := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '(', ')', '.', '\t':
+			return -1
+		}
+		return r
+	}, msisdn)
+
+	intl := false
+	switch {
+	case strings.HasPrefix(d, "+"):
+		d, intl = d[1:], true
+	case strings.HasPrefix(d, "00"):
+		d, intl = d[2:], true
+	}
+	if d == "" || strings.IndexFunc(d, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return msisdn, ErrInvalidMSISDN
+	}
+
+	cc, n := p.CountryCode, p.NationalLength
+	if !intl && cc != "" {
+		switch {
+		case p.NationalPrefix != "" && strings.HasPrefix(d, p.NationalPrefix) &&
+			(n == 0 || len(d) == len(p.NationalPrefix)+n):
+			d = cc + d[len(p.NationalPrefix):]
+		case strings.HasPrefix(d, cc) && (n == 0 || len(d) == len(cc)+n):
+			// already international without "+"
+		case n == 0 || len(d) == n:
+			d = cc + d
+		}
+	}
+
+	if len(d) < 8 || len(d) > 15 {
+		return msisdn, ErrInvalidMSISDN
+	}
+	if cc != "" && n > 0 && strings.HasPrefix(d, cc) && len(d) != len(cc)+n {
+		return msisdn, ErrInvalidMSISDN
+	}
+	if p.Plus {
+		return "+" + d, nil
+	}
+	return d, nil
+}
+
+var msisdnPolicy atomic.Pointer[MSISDNPolicy]
+
+// SetMSISDNPolicy installs the default normalization for handlers in this
+// package and its subpackages that have no Policy of their own. Pass nil to
+// forward numbers unchanged (default).
+//
+//	transport.SetMSISDNPolicy(&transport.MSISDNPolicy{CountryCode: "258", NationalLength: 9, Plus: true})
+func SetMSISDNPolicy(p *MSISDNPolicy) { msisdnPolicy.Store(p) }
+
+// NormalizeRequest applies the default policy (SetMSISDNPolicy) to req.
+func NormalizeRequest(req *core.Request) error { return msisdnPolicy.Load().Apply(req) }
+
+// Apply normalizes req.Msisdn, keeping the original in Meta["msisdn_raw"].
+// Invalid numbers pass through unchanged unless the policy is Strict, in which
+// case ErrInvalidMSISDN is returned. A nil policy leaves req alone.
+func (p *MSISDNPolicy) Apply(req *core.Request) error {
+	if p == nil || req.Msisdn == "" {
+		return nil
+	}
+	norm, err := p.Normalize(req.Msisdn)
+	if err != nil {
+		if p.Strict {
+			return err
+		}
+		return nil
+	}
+	if req.Meta == nil {
+		req.Meta = map[string]string{}
+	}
+	req.Meta["msisdn_raw"] = req.Msisdn
+	req.Msisdn = norm
+	return nil
+}
@@ -0,0 +1,91 @@
+package transport
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/grahms/cardinal/core"
+)
+
+func TestMSISDNPolicyNormalize(t *testing.T) {
+	mz := MSISDNPolicy{CountryCode: "258", NationalPrefix: "0", NationalLength: 9, Plus: true}
+	tests := []struct {
+		name   string
+		policy MSISDNPolicy
+		in     string
+		want   string
+		err    bool
+	}{
+		{"e164", mz, "+258840000001", "+258840000001", false},
+		{"international no plus", mz, "258840000001", "+258840000001", false},
+		{"00 prefix", mz, "00258840000001", "+258840000001", false},
+		{"national", mz, "840000001", "+258840000001", false},
+		{"trunk prefix", mz, "0840000001", "+258840000001", false},
+		{"separators", mz, "+258 84-000 (0001)", "+258840000001", false},
+		{"without plus", MSISDNPolicy{CountryCode: "258", NationalLength: 9}, "840000001", "258840000001", false},
+		{"other country", mz, "+27820000001", "+27820000001", false},
+		{"too short", mz, "12345", "12345", true},
+		{"wrong national length", mz, "+25884000000", "+25884000000", true},
+		{"letters", mz, "84000000a", "84000000a", true},
+		{"empty", mz, "", "", true},
+		{"no country code", MSISDNPolicy{Plus: true}, "258840000001", "+258840000001", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.policy.Normalize(tt.in)
+			if (err != nil) != tt.err {
+				t.Fatalf("Normalize(%q) error = %v, want error %v", tt.in, err, tt.err)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidMSISDN) {
+				t.Fatalf("error = %v, want ErrInvalidMSISDN", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMSISDNPolicyApply(t *testing.T) {
+	p := &MSISDNPolicy{CountryCode: "258", NationalLength: 9, Plus: true}
+	req := core.Request{Msisdn: "840000001"}
+	if err := p.Apply(&req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Msisdn != "+258840000001" || req.Meta["msisdn_raw"] != "840000001" {
+		t.Fatalf("got Msisdn %q, raw %q", req.Msisdn, req.Meta["msisdn_raw"])
+	}
+
+	req = core.Request{Msisdn: "bogus"}
+	if err := p.Apply(&req); err != nil || req.Msisdn != "bogus" {
+		t.Fatalf("lenient policy: err %v, Msisdn %q", err, req.Msisdn)
+	}
+	strict := *p
+	strict.Strict = true
+	if err := strict.Apply(&req); !errors.Is(err, ErrInvalidMSISDN) {
+		t.Fatalf("strict policy: err = %v", err)
+	}
+	if err := (*MSISDNPolicy)(nil).Apply(&req); err != nil {
+		t.Fatalf("nil policy: %v", err)
+	}
+}
+
+func TestHandlerMSISDNPolicy(t *testing.T) {
+	mz := &MSISDNPolicy{CountryCode: "258", NationalLength: 9, Plus: true}
+	ao := &MSISDNPolicy{CountryCode: "244", NationalLength: 9, Plus: true}
+	form := url.Values{"sessionId": {"s"}, "phoneNumber": {"840000001"}}
+
+	var a, b capture
+	postForm(AfricaTalkingHandler(&a, ATPolicy(Policy{MSISDN: mz})), form)
+	postForm(AfricaTalkingHandler(&b, ATPolicy(Policy{MSISDN: ao})), form)
+	if a.req.Msisdn != "+258840000001" || b.req.Msisdn != "+244840000001" {
+		t.Fatalf("per-handler policies: got %q and %q", a.req.Msisdn, b.req.Msisdn)
+	}
+
+	var c capture
+	postForm(AfricaTalkingHandler(&c), form)
+	if c.req.Msisdn != "840000001" {
+		t.Fatalf("no policy: got %q, want the number unchanged", c.req.Msisdn)
+	}
+}
@@ -0,0 +1,143 @@
+package transport
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/grahms/cardinal/core"
+)
+
+// MTNHandler handles MTN USSD gateway callbacks (JSON).
+// Example shape (customize via options if your instance differs):
+//
+//	IN:  {"sessionId":"MTN-1", "msisdn":"23324...", "serviceCode":"*144#",
+//	      "messageType":"0", "ussdString":"*144#"}
+//	OUT: {"sessionId":"MTN-1", "msisdn":"23324...", "serviceCode":"*144#",
+//	      "messageType":"1", "ussdString":"<message>"}
+//
+// messageType: "0" begin, "1" continue, "2" end (reply only), "3" abort; they map
+// to core.Request.Kind. On begin the dialled string is not treated as input, so
+// the start screen is shown. The raw messageType is kept in Request.Meta["messageType"].
+func MTNHandler(eng core.Handler, opts ...MTNOption) http.Handler {
+	cfg := mtnConfig{
+		FieldSessionID:   "sessionId",
+		FieldMsisdn:      "msisdn",
+		FieldServiceCode: "serviceCode",
+		FieldText:        "ussdString",
+		FieldType:        "messageType",
+		TypeBegin:        "0",
+		TypeContinue:     "1",
+		TypeEnd:          "2",
+		TypeAbort:        "3",
+	}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, "bad json", http.StatusBadRequest)
+			return
+		}
+		typ := strings.TrimSpace(asString(in[cfg.FieldType]))
+		req := core.Request{
+			SessionID:   asString(in[cfg.FieldSessionID]),
+			Msisdn:      strings.TrimSpace(asString(in[cfg.FieldMsisdn])),
+			ServiceCode: strings.TrimSpace(asString(in[cfg.FieldServiceCode])),
+			Text:        strings.TrimSpace(asString(in[cfg.FieldText])),
+			Meta: map[string]string{
+				"vendor":      "mtn",
+				"ip":          cfg.Policy.ClientIP(r),
+				"messageType": typ,
+			},
+		}
+		switch typ {
+		case cfg.TypeBegin:
+			req.Kind = core.KindBegin
+			req.Text = ""
+		case cfg.TypeContinue:
+			req.Kind = core.KindContinue
+		case cfg.TypeAbort:
+			req.Kind = core.KindAbort
+		}
+		if err := cfg.Policy.Normalize(&req); err != nil {
+			http.Error(w, "bad msisdn", http.StatusBadRequest)
+			return
+		}
+		rep, _ := eng.Handle(r.Context(), req)
+
+		out := map[string]any{
+			cfg.FieldSessionID:   req.SessionID,
+			cfg.FieldMsisdn:      req.Msisdn,
+			cfg.FieldServiceCode: req.ServiceCode,
+			cfg.FieldText:        rep.Message,
+		}
+		if rep.Continue {
+			out[cfg.FieldType] = cfg.TypeContinue
+		} else {
+			out[cfg.FieldType] = cfg.TypeEnd
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(out)
+	})
+}
+
+type mtnConfig struct {
+	FieldSessionID   string
+	FieldMsisdn      string
+	FieldServiceCode string
+	FieldText        string
+	FieldType        string
+
+	TypeBegin    string
+	TypeContinue string
+	TypeEnd      string
+	TypeAbort    string
+
+	Policy Policy
+}
+type MTNOption func(*mtnConfig)
+
+// MTNPolicy sets this handler's request policy (default: the package defaults).
+func MTNPolicy(p Policy) MTNOption { return func(c *mtnConfig) { c.Policy = p } }
+
+// MTNFields overrides the request/response field names. Empty values keep the default.
+func MTNFields(sessionID, msisdn, serviceCode, text string) MTNOption {
+	return func(c *mtnConfig) {
+		if sessionID != "" {
+			c.FieldSessionID = sessionID
+		}
+		if msisdn != "" {
+			c.FieldMsisdn = msisdn
+		}
+		if serviceCode != "" {
+			c.FieldServiceCode = serviceCode
+		}
+		if text != "" {
+			c.FieldText = text
+		}
+	}
+}
+
+// MTNMessageType overrides the message-type field and its values.
+// Example: MTNMessageType("type", "BEGIN", "CONTINUE", "END", "ABORT").
+func MTNMessageType(field, begin, cont, end, abort string) MTNOption {
+	return func(c *mtnConfig) {
+		if field != "" {
+			c.FieldType = field
+		}
+		if begin != "" {
+			c.TypeBegin = begin
+		}
+		if cont != "" {
+			c.TypeContinue = cont
+		}
+		if end != "" {
+			c.TypeEnd = end
+		}
+		if abort != "" {
+			c.TypeAbort = abort
+		}
+	}
+}
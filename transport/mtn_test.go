@@ -0,0 +1,118 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grahms/cardinal/core"
+)
+
+func TestMTNHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []MTNOption
+		in       string     // request payload
+		rep      core.Reply // engine reply
+		wantKind core.Kind
+		wantText string
+		out      string // golden response payload
+	}{
+		{
+			name:     "begin",
+			in:       `{"sessionId":"MTN-1","msisdn":"233240000001","serviceCode":"*144#","messageType":"0","ussdString":"*144#"}`,
+			rep:      core.CON("Welcome\n1) Balance"),
+			wantKind: core.KindBegin,
+			out:      `{"messageType":"1","msisdn":"233240000001","serviceCode":"*144#","sessionId":"MTN-1","ussdString":"Welcome\n1) Balance"}`,
+		},
+		{
+			name:     "continue",
+			in:       `{"sessionId":"MTN-1","msisdn":"233240000001","serviceCode":"*144#","messageType":"1","ussdString":"1"}`,
+			rep:      core.CON("Enter amount"),
+			wantKind: core.KindContinue,
+			wantText: "1",
+			out:      `{"messageType":"1","msisdn":"233240000001","serviceCode":"*144#","sessionId":"MTN-1","ussdString":"Enter amount"}`,
+		},
+		{
+			name:     "end",
+			in:       `{"sessionId":"MTN-1","msisdn":"233240000001","serviceCode":"*144#","messageType":"1","ussdString":"100"}`,
+			rep:      core.END("Top-up successful"),
+			wantKind: core.KindContinue,
+			wantText: "100",
+			out:      `{"messageType":"2","msisdn":"233240000001","serviceCode":"*144#","sessionId":"MTN-1","ussdString":"Top-up successful"}`,
+		},
+		{
+			name:     "abort",
+			in:       `{"sessionId":"MTN-1","msisdn":"233240000001","serviceCode":"*144#","messageType":"3","ussdString":""}`,
+			rep:      core.END(""),
+			wantKind: core.KindAbort,
+			out:      `{"messageType":"2","msisdn":"233240000001","serviceCode":"*144#","sessionId":"MTN-1","ussdString":""}`,
+		},
+		{
+			name:     "MTNFields",
+			opts:     []MTNOption{MTNFields("sid", "from", "code", "input")},
+			in:       `{"sid":"MTN-2","from":"233240000002","code":"*155#","messageType":"1","input":"2"}`,
+			rep:      core.CON("Menu"),
+			wantKind: core.KindContinue,
+			wantText: "2",
+			out:      `{"code":"*155#","from":"233240000002","input":"Menu","messageType":"1","sid":"MTN-2"}`,
+		},
+		{
+			name:     "MTNMessageType begin",
+			opts:     []MTNOption{MTNMessageType("type", "BEGIN", "CONTINUE", "END", "ABORT")},
+			in:       `{"sessionId":"MTN-3","msisdn":"233240000003","serviceCode":"*144#","type":"BEGIN","ussdString":"*144#"}`,
+			rep:      core.CON("Welcome"),
+			wantKind: core.KindBegin,
+			out:      `{"msisdn":"233240000003","serviceCode":"*144#","sessionId":"MTN-3","type":"CONTINUE","ussdString":"Welcome"}`,
+		},
+		{
+			name:     "MTNMessageType end",
+			opts:     []MTNOption{MTNMessageType("type", "BEGIN", "CONTINUE", "END", "ABORT")},
+			in:       `{"sessionId":"MTN-3","msisdn":"233240000003","serviceCode":"*144#","type":"CONTINUE","ussdString":"9"}`,
+			rep:      core.END("Bye"),
+			wantKind: core.KindContinue,
+			wantText: "9",
+			out:      `{"msisdn":"233240000003","serviceCode":"*144#","sessionId":"MTN-3","type":"END","ussdString":"Bye"}`,
+		},
+		{
+			name:     "MTNMessageType abort",
+			opts:     []MTNOption{MTNMessageType("type", "BEGIN", "CONTINUE", "END", "ABORT")},
+			in:       `{"sessionId":"MTN-3","msisdn":"233240000003","serviceCode":"*144#","type":"ABORT"}`,
+			rep:      core.END(""),
+			wantKind: core.KindAbort,
+			out:      `{"msisdn":"233240000003","serviceCode":"*144#","sessionId":"MTN-3","type":"END","ussdString":""}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &capture{rep: tt.rep}
+			w := httptest.NewRecorder()
+			MTNHandler(c, tt.opts...).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ussd/mtn", strings.NewReader(tt.in)))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %q", w.Code, w.Body.String())
+			}
+			if c.req.Kind != tt.wantKind {
+				t.Errorf("Kind = %v, want %v", c.req.Kind, tt.wantKind)
+			}
+			if c.req.Text != tt.wantText {
+				t.Errorf("Text = %q, want %q", c.req.Text, tt.wantText)
+			}
+			if got := strings.TrimSpace(w.Body.String()); got != tt.out {
+				t.Errorf("response:\n got %s\nwant %s", got, tt.out)
+			}
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+				t.Errorf("Content-Type = %q", ct)
+			}
+		})
+	}
+}
+
+func TestMTNHandlerBadJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	MTNHandler(&capture{}).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ussd/mtn", strings.NewReader("{")))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", w.Code)
+	}
+}
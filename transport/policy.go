@@ -0,0 +1,35 @@
+package transport
+
+import (
+	"net/http"
+
+	"github.com/grahms/cardinal/core"
+)
+
+// Policy holds per-handler request policies. Nil fields fall back to the
+// package defaults, so adapters (or dispatcher services) in one process can
+// differ and tests don't have to touch globals. Proxies is only used by HTTP
+// handlers:
+//
+//	mz := &transport.MSISDNPolicy{CountryCode: "258", NationalLength: 9, Plus: true}
+//	at := transport.AfricaTalkingHandler(eng, transport.ATPolicy(transport.Policy{MSISDN: mz}))
+type Policy struct {
+	MSISDN  *MSISDNPolicy   // default: the policy set with SetMSISDNPolicy
+	Proxies *TrustedProxies // default: the proxies set with SetTrustedProxies
+}
+
+// Normalize applies the MSISDN policy to req (see MSISDNPolicy.Apply).
+func (p Policy) Normalize(req *core.Request) error {
+	if p.MSISDN != nil {
+		return p.MSISDN.Apply(req)
+	}
+	return NormalizeRequest(req)
+}
+
+// ClientIP returns r's client IP, honouring the trusted proxies.
+func (p Policy) ClientIP(r *http.Request) string {
+	if p.Proxies != nil {
+		return p.Proxies.ClientIP(r)
+	}
+	return clientIP(r)
+}
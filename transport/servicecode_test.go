@@ -0,0 +1,70 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/grahms/cardinal/core"
+)
+
+// capture records the last request it handled and answers with rep.
+type capture struct {
+	req core.Request
+	rep core.Reply
+}
+
+func (c *capture) Handle(_ context.Context, req core.Request) (core.Reply, error) {
+	c.req = req
+	return c.rep, nil
+}
+
+func postForm(h http.Handler, v url.Values) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(v.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func postJSON(h http.Handler, body string) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func TestServiceCode(t *testing.T) {
+	tests := []struct {
+		name string
+		send func(h core.Handler)
+	}{
+		{"africastalking", func(h core.Handler) {
+			postForm(AfricaTalkingHandler(h), url.Values{"sessionId": {"s"}, "phoneNumber": {"+258840000001"}, "serviceCode": {"*144#"}})
+		}},
+		{"africastalking/ATFields", func(h core.Handler) {
+			postForm(AfricaTalkingHandler(h, ATFields("", "", "", "code")), url.Values{"sessionId": {"s"}, "code": {"*144#"}})
+		}},
+		{"infobip", func(h core.Handler) {
+			postForm(InfobipFormHandler(h), url.Values{"SESSION_ID": {"s"}, "MSISDN": {"+258840000001"}, "SERVICE_CODE": {"*144#"}})
+		}},
+		{"infobip/IBFields", func(h core.Handler) {
+			postForm(InfobipFormHandler(h, IBFields("", "", "", "SHORTCODE")), url.Values{"SESSION_ID": {"s"}, "SHORTCODE": {"*144#"}})
+		}},
+		{"vodacom", func(h core.Handler) {
+			postJSON(VodacomHandler(h), `{"sessionId":"s","msisdn":"+258840000001","serviceCode":"*144#","userInput":""}`)
+		}},
+		{"vodacom/VodaFields", func(h core.Handler) {
+			postJSON(VodacomHandler(h, VodaFields("", "", "", "ussdCode")), `{"sessionId":"s","ussdCode":"*144#"}`)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c capture
+			tt.send(&c)
+			if c.req.ServiceCode != "*144#" {
+				t.Fatalf("ServiceCode = %q, want %q", c.req.ServiceCode, "*144#")
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+// Package smpp is a USSD transport over SMPP 3.4.
+//
+// The Client binds to an SMSC as a transceiver ESME, turns USSD deliver_sm
+// PDUs (ussd_service_op TLV) into core.Request and answers each one with a
+// submit_sm: USSR request to continue, PSSR response to release the session.
+//
+//	cli := smpp.New(eng, smpp.Config{Addr: "smsc:2775", SystemID: "cardinal", Password: "secret"})
+//	go cli.Run(ctx) // binds, keeps the link alive and reconnects until ctx is done
+package smpp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/transport"
+)
+
+// Config for the SMPP client.
+type Config struct {
+	Addr       string // host:port of the SMSC
+	SystemID   string
+	Password   string
+	SystemType string
+
+	EnquireLink    time.Duration // keep-alive interval (default 30s)
+	ReconnectDelay time.Duration // wait between reconnects (default 5s)
+	DialTimeout    time.Duration // default 10s
+
+	// SessionKey derives core.Request.SessionID from an inbound message.
+	// Default: "<source>:<its_session_info session number>" (or just source).
+	SessionKey func(ShortMessage) string
+
+	// MSISDN normalizes source addresses (default: transport.SetMSISDNPolicy).
+	MSISDN *transport.MSISDNPolicy
+
+	Logger *log.Logger // default log.Default()
+}
+
+// Client is an ESME that serves a core.Engine over SMPP.
+type Client struct {
+	cfg Config
+	eng core.Handler
+	seq atomic.Uint32
+
+	mu   sync.Mutex // guards conn writes
+	conn net.Conn
+}
+
+func New(eng core.Handler, cfg Config) *Client {
+	if cfg.EnquireLink <= 0 {
+		cfg.EnquireLink = 30 * time.Second
+	}
+	if cfg.ReconnectDelay <= 0 {
+		cfg.ReconnectDelay = 5 * time.Second
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = 10 * time.Second
+	}
+	if cfg.SessionKey == nil {
+		cfg.SessionKey = defaultSessionKey
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.Default()
+	}
+	return &Client{cfg: cfg, eng: eng}
+}
+
+// Run binds and serves until ctx is cancelled, reconnecting after failures.
+func (c *Client) Run(ctx context.Context) error {
+	for {
+		err := c.session(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		c.cfg.Logger.Printf("smpp: link to %s lost: %v (reconnecting in %s)", c.cfg.Addr, err, c.cfg.ReconnectDelay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.cfg.ReconnectDelay):
+		}
+	}
+}
+
+// session runs one bound connection until it fails or ctx is done.
+func (c *Client) session(ctx context.Context) error {
+	d := net.Dialer{Timeout: c.cfg.DialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", c.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	c.mu.Lock()
+	c.conn = conn
+	c.mu.Unlock()
+
+	if err := c.bind(conn); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		_ = c.send(&PDU{ID: Unbind, Seq: c.nextSeq()})
+		_ = conn.Close()
+	}()
+	go c.keepAlive(ctx)
+
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(3 * c.cfg.EnquireLink))
+		p, err := ReadPDU(conn)
+		if err != nil {
+			return err
+		}
+		switch p.ID {
+		case DeliverSM:
+			_ = c.send(&PDU{ID: DeliverSMResp, Seq: p.Seq, Body: []byte{0}})
+			sm, err := ParseShortMessage(p.Body)
+			if err != nil {
+				c.cfg.Logger.Printf("smpp: bad deliver_sm: %v", err)
+				continue
+			}
+			go c.dispatch(ctx, sm)
+		case EnquireLink:
+			_ = c.send(&PDU{ID: EnquireLinkResp, Seq: p.Seq})
+		case Unbind:
+			_ = c.send(&PDU{ID: UnbindResp, Seq: p.Seq})
+			return errors.New("unbound by smsc")
+		case SubmitSMResp:
+			if p.Status != StatusOK {
+				c.cfg.Logger.Printf("smpp: submit_sm seq=%d rejected status=0x%08x", p.Seq, p.Status)
+			}
+		case EnquireLinkResp, UnbindResp, GenericNack:
+		default:
+			if p.ID&0x80000000 == 0 {
+				_ = c.send(&PDU{ID: GenericNack, Status: StatusInvCmdID, Seq: p.Seq})
+			}
+		}
+	}
+}
+
+func (c *Client) bind(conn net.Conn) error {
+	seq := c.nextSeq()
+	body := Bind{SystemID: c.cfg.SystemID, Password: c.cfg.Password, SystemType: c.cfg.SystemType, Version: 0x34}.Bytes()
+	if err := c.send(&PDU{ID: BindTransceiver, Seq: seq, Body: body}); err != nil {
+		return err
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(c.cfg.DialTimeout))
+	p, err := ReadPDU(conn)
+	if err != nil {
+		return err
+	}
+	if p.ID != BindTransceiverResp || p.Seq != seq {
+		return fmt.Errorf("smpp: unexpected bind reply 0x%08x", uint32(p.ID))
+	}
+	if p.Status != StatusOK {
+		return fmt.Errorf("smpp: bind rejected status=0x%08x", p.Status)
+	}
+	return nil
+}
+
+func (c *Client) keepAlive(ctx context.Context) {
+	t := time.NewTicker(c.cfg.EnquireLink)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := c.send(&PDU{ID: EnquireLink, Seq: c.nextSeq()}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// dispatch runs one USSD step through the engine and submits the reply.
+func (c *Client) dispatch(ctx context.Context, sm ShortMessage) {
+	op, _ := sm.ServiceOp()
+	text := sm.Text()
+	req := core.Request{
+		SessionID:   c.cfg.SessionKey(sm),
+		Msisdn:      sm.Source,
+		ServiceCode: sm.Dest,
+		Text:        text,
+		Meta: map[string]string{
+			"vendor":          "smpp",
+			"ussd_service_op": strconv.Itoa(int(op)),
+		},
+	}
+	if err := (transport.Policy{MSISDN: c.cfg.MSISDN}).Normalize(&req); err != nil {
+		c.cfg.Logger.Printf("smpp: dropping message from %q: %v", sm.Source, err)
+		return
+	}
+	switch op {
+	case OpPSSRIndication:
+		// begin: the dialled string is not user input
+		req.Kind = core.KindBegin
+		req.Meta["ussdString"] = text
+		req.Text = ""
+	case OpUSSRConfirm:
+		req.Kind = core.KindContinue
+	}
+	if its, ok := sm.TLVs[TagITSSessionInfo]; ok && len(its) == 2 && its[1]&itsEndOfSession != 0 {
+		// MSC flagged end of dialogue (user cancelled): clean up, nothing to send
+		req.Kind = core.KindAbort
+		_, _ = c.eng.Handle(ctx, req)
+		return
+	}
+	rep, _ := c.eng.Handle(ctx, req)
+
+	dc, msg := encodeText(rep.Message)
+	out := ShortMessage{
+		SourceTON: sm.DestTON, SourceNPI: sm.DestNPI, Source: sm.Dest,
+		DestTON: sm.SourceTON, DestNPI: sm.SourceNPI, Dest: sm.Source,
+		DataCoding: dc,
+		Message:    msg,
+		TLVs:       map[uint16][]byte{TagUSSDServiceOp: {OpUSSRRequest}},
+	}
+	if !rep.Continue {
+		out.TLVs[TagUSSDServiceOp] = []byte{OpPSSRResponse}
+	}
+	if its, ok := sm.TLVs[TagITSSessionInfo]; ok && len(its) == 2 {
+		v := []byte{its[0], its[1] &^ itsEndOfSession}
+		if !rep.Continue {
+			v[1] |= itsEndOfSession
+		}
+		out.TLVs[TagITSSessionInfo] = v
+	}
+	if err := c.send(&PDU{ID: SubmitSM, Seq: c.nextSeq(), Body: out.Bytes()}); err != nil {
+		c.cfg.Logger.Printf("smpp: submit_sm to %s failed: %v", sm.Source, err)
+	}
+}
+
+func (c *Client) send(p *PDU) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return errors.New("smpp: not connected")
+	}
+	_, err := c.conn.Write(p.Bytes())
+	return err
+}
+
+func (c *Client) nextSeq() uint32 {
+	// sequence numbers are 1..0x7FFFFFFF
+	for {
+		if n := c.seq.Add(1) & 0x7FFFFFFF; n != 0 {
+			return n
+		}
+	}
+}
+
+func defaultSessionKey(sm ShortMessage) string {
+	if its, ok := sm.TLVs[TagITSSessionInfo]; ok && len(its) > 0 {
+		return sm.Source + ":" + strconv.Itoa(int(its[0]))
+	}
+	return sm.Source
+}
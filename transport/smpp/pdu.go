@@ -0,0 +1,350 @@
+package smpp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"unicode/utf16"
+)
+
+// CommandID identifies an SMPP 3.4 operation.
+type CommandID uint32
+
+const (
+	GenericNack         CommandID = 0x80000000
+	BindTransceiver     CommandID = 0x00000009
+	BindTransceiverResp CommandID = 0x80000009
+	SubmitSM            CommandID = 0x00000004
+	SubmitSMResp        CommandID = 0x80000004
+	DeliverSM           CommandID = 0x00000005
+	DeliverSMResp       CommandID = 0x80000005
+	Unbind              CommandID = 0x00000006
+	UnbindResp          CommandID = 0x80000006
+	EnquireLink         CommandID = 0x00000015
+	EnquireLinkResp     CommandID = 0x80000015
+)
+
+// Command status codes used here.
+const (
+	StatusOK       uint32 = 0x00000000
+	StatusInvCmdID uint32 = 0x00000003
+	StatusSysErr   uint32 = 0x00000008
+	StatusBindFail uint32 = 0x0000000D
+)
+
+// Optional parameter tags.
+const (
+	TagMessagePayload uint16 = 0x0424
+	TagUSSDServiceOp  uint16 = 0x0501
+	TagITSSessionInfo uint16 = 0x1383
+)
+
+const (
+	maxPDULength    = 64 * 1024
+	headerLength    = 16
+	maxShortMessage = 254 // longer bodies travel in message_payload
+	dataCodingSMSC  = byte(0x00)
+	dataCodingUCS2  = byte(0x08)
+	itsEndOfSession = byte(0x01)
+)
+
+// USSD service operations (ussd_service_op TLV values).
+const (
+	OpPSSDIndication byte = 0
+	OpPSSRIndication byte = 1 // MO: user dialled a code (session begin)
+	OpUSSRRequest    byte = 2 // MT: show screen and wait for input (continue)
+	OpUSSNRequest    byte = 3
+	OpPSSDResponse   byte = 16
+	OpPSSRResponse   byte = 17 // MT: final screen, release the session (end)
+	OpUSSRConfirm    byte = 18 // MO: user's answer to a USSR request
+	OpUSSNConfirm    byte = 19
+)
+
+// PDU is a raw SMPP protocol data unit.
+type PDU struct {
+	ID     CommandID
+	Status uint32
+	Seq    uint32
+	Body   []byte
+}
+
+// ReadPDU reads one PDU from r.
+func ReadPDU(r io.Reader) (*PDU, error) {
+	var hdr [headerLength]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(hdr[0:4])
+	if n < headerLength || n > maxPDULength {
+		return nil, fmt.Errorf("smpp: invalid pdu length %d", n)
+	}
+	p := &PDU{
+		ID:     CommandID(binary.BigEndian.Uint32(hdr[4:8])),
+		Status: binary.BigEndian.Uint32(hdr[8:12]),
+		Seq:    binary.BigEndian.Uint32(hdr[12:16]),
+		Body:   make([]byte, n-headerLength),
+	}
+	if _, err := io.ReadFull(r, p.Body); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Bytes encodes the PDU with its header.
+func (p *PDU) Bytes() []byte {
+	out := make([]byte, headerLength+len(p.Body))
+	binary.BigEndian.PutUint32(out[0:4], uint32(len(out)))
+	binary.BigEndian.PutUint32(out[4:8], uint32(p.ID))
+	binary.BigEndian.PutUint32(out[8:12], p.Status)
+	binary.BigEndian.PutUint32(out[12:16], p.Seq)
+	copy(out[headerLength:], p.Body)
+	return out
+}
+
+// Bind is the body of bind_* requests.
+type Bind struct {
+	SystemID     string
+	Password     string
+	SystemType   string
+	Version      byte
+	AddrTON      byte
+	AddrNPI      byte
+	AddressRange string
+}
+
+func (b Bind) Bytes() []byte {
+	var w bytes.Buffer
+	cstr(&w, b.SystemID)
+	cstr(&w, b.Password)
+	cstr(&w, b.SystemType)
+	w.WriteByte(b.Version)
+	w.WriteByte(b.AddrTON)
+	w.WriteByte(b.AddrNPI)
+	cstr(&w, b.AddressRange)
+	return w.Bytes()
+}
+
+func ParseBind(body []byte) (Bind, error) {
+	r := &reader{b: body}
+	b := Bind{
+		SystemID:   r.cstr(),
+		Password:   r.cstr(),
+		SystemType: r.cstr(),
+		Version:    r.byte(),
+		AddrTON:    r.byte(),
+		AddrNPI:    r.byte(),
+	}
+	b.AddressRange = r.cstr()
+	return b, r.err
+}
+
+// ShortMessage is the body of submit_sm and deliver_sm.
+type ShortMessage struct {
+	ServiceType          string
+	SourceTON, SourceNPI byte
+	Source               string
+	DestTON, DestNPI     byte
+	Dest                 string
+	ESMClass             byte
+	ProtocolID           byte
+	Priority             byte
+	ScheduleDeliveryTime string
+	ValidityPeriod       string
+	RegisteredDelivery   byte
+	ReplaceIfPresent     byte
+	DataCoding           byte
+	SMDefaultMsgID       byte
+	Message              []byte
+	TLVs                 map[uint16][]byte
+}
+
+// Bytes encodes the body. Messages over 254 bytes are sent in the
+// message_payload TLV with sm_length 0, as SMPP 3.4 requires.
+func (m ShortMessage) Bytes() []byte {
+	var w bytes.Buffer
+	cstr(&w, m.ServiceType)
+	w.WriteByte(m.SourceTON)
+	w.WriteByte(m.SourceNPI)
+	cstr(&w, m.Source)
+	w.WriteByte(m.DestTON)
+	w.WriteByte(m.DestNPI)
+	cstr(&w, m.Dest)
+	w.WriteByte(m.ESMClass)
+	w.WriteByte(m.ProtocolID)
+	w.WriteByte(m.Priority)
+	cstr(&w, m.ScheduleDeliveryTime)
+	cstr(&w, m.ValidityPeriod)
+	w.WriteByte(m.RegisteredDelivery)
+	w.WriteByte(m.ReplaceIfPresent)
+	w.WriteByte(m.DataCoding)
+	w.WriteByte(m.SMDefaultMsgID)
+	tlvs := m.TLVs
+	if len(m.Message) > maxShortMessage {
+		tlvs = make(map[uint16][]byte, len(m.TLVs)+1)
+		for t, v := range m.TLVs {
+			tlvs[t] = v
+		}
+		tlvs[TagMessagePayload] = m.Message
+		w.WriteByte(0)
+	} else {
+		w.WriteByte(byte(len(m.Message)))
+		w.Write(m.Message)
+	}
+
+	tags := make([]int, 0, len(tlvs))
+	for t := range tlvs {
+		tags = append(tags, int(t))
+	}
+	sort.Ints(tags)
+	for _, t := range tags {
+		v := tlvs[uint16(t)]
+		_ = binary.Write(&w, binary.BigEndian, uint16(t))
+		_ = binary.Write(&w, binary.BigEndian, uint16(len(v)))
+		w.Write(v)
+	}
+	return w.Bytes()
+}
+
+// ParseShortMessage decodes a submit_sm/deliver_sm body. A message_payload
+// TLV (with sm_length 0) is moved into Message.
+func ParseShortMessage(body []byte) (ShortMessage, error) {
+	r := &reader{b: body}
+	m := ShortMessage{
+		ServiceType: r.cstr(),
+		SourceTON:   r.byte(),
+		SourceNPI:   r.byte(),
+		Source:      r.cstr(),
+		DestTON:     r.byte(),
+		DestNPI:     r.byte(),
+		Dest:        r.cstr(),
+		ESMClass:    r.byte(),
+		ProtocolID:  r.byte(),
+		Priority:    r.byte(),
+	}
+	m.ScheduleDeliveryTime = r.cstr()
+	m.ValidityPeriod = r.cstr()
+	m.RegisteredDelivery = r.byte()
+	m.ReplaceIfPresent = r.byte()
+	m.DataCoding = r.byte()
+	m.SMDefaultMsgID = r.byte()
+	m.Message = r.bytes(int(r.byte()))
+	m.TLVs = map[uint16][]byte{}
+	for r.err == nil && r.off < len(r.b) {
+		tag := r.u16()
+		v := r.bytes(int(r.u16()))
+		if r.err == nil {
+			m.TLVs[tag] = v
+		}
+	}
+	if p, ok := m.TLVs[TagMessagePayload]; ok && len(m.Message) == 0 {
+		m.Message = p
+		delete(m.TLVs, TagMessagePayload)
+	}
+	return m, r.err
+}
+
+// ServiceOp returns the ussd_service_op TLV, if present.
+func (m ShortMessage) ServiceOp() (byte, bool) {
+	v, ok := m.TLVs[TagUSSDServiceOp]
+	if !ok || len(v) != 1 {
+		return 0, false
+	}
+	return v[0], true
+}
+
+// Text decodes Message (or the message_payload TLV if Message is empty)
+// according to DataCoding (UCS-2 or 8-bit default).
+func (m ShortMessage) Text() string {
+	msg := m.Message
+	if len(msg) == 0 {
+		msg = m.TLVs[TagMessagePayload]
+	}
+	if m.DataCoding != dataCodingUCS2 {
+		return string(msg)
+	}
+	u := make([]uint16, len(msg)/2)
+	for i := range u {
+		u[i] = binary.BigEndian.Uint16(msg[2*i:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// encodeText picks the default alphabet for ASCII and UCS-2 otherwise.
+func encodeText(s string) (byte, []byte) {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return dataCodingSMSC, []byte(s)
+	}
+	u := utf16.Encode([]rune(s))
+	out := make([]byte, 2*len(u))
+	for i, c := range u {
+		binary.BigEndian.PutUint16(out[2*i:], c)
+	}
+	return dataCodingUCS2, out
+}
+
+/* ---------- helpers ---------- */
+
+var errShort = errors.New("smpp: truncated pdu")
+
+type reader struct {
+	b   []byte
+	off int
+	err error
+}
+
+func (r *reader) byte() byte {
+	if r.err != nil || r.off >= len(r.b) {
+		r.err = errShort
+		return 0
+	}
+	c := r.b[r.off]
+	r.off++
+	return c
+}
+
+func (r *reader) u16() uint16 {
+	v := r.bytes(2)
+	if r.err != nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(v)
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil || r.off+n > len(r.b) {
+		r.err = errShort
+		return nil
+	}
+	v := append([]byte(nil), r.b[r.off:r.off+n]...)
+	r.off += n
+	return v
+}
+
+func (r *reader) cstr() string {
+	if r.err != nil {
+		return ""
+	}
+	i := bytes.IndexByte(r.b[r.off:], 0)
+	if i < 0 {
+		r.err = errShort
+		return ""
+	}
+	s := string(r.b[r.off : r.off+i])
+	r.off += i + 1
+	return s
+}
+
+func cstr(w *bytes.Buffer, s string) {
+	w.WriteString(s)
+	w.WriteByte(0)
+}
@@ -0,0 +1,151 @@
+package smpp
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grahms/cardinal/core"
+	"github.com/grahms/cardinal/router"
+	"github.com/grahms/cardinal/store"
+)
+
+func TestShortMessageRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		payload bool // expect the body in message_payload
+	}{
+		{"ascii", "Welcome\n1) Balance", false},
+		{"ucs2", "Olá", false},
+		{"max sm_length", strings.Repeat("a", 254), false},
+		{"ascii over 254", strings.Repeat("a", 255), true},
+		{"ucs2 over 254", strings.Repeat("ç", 127) + "\n⚠️ Opção inválida.", true},
+		{"ucs2 300 bytes", strings.Repeat("é", 150), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dc, msg := encodeText(tt.text)
+			in := ShortMessage{
+				Source: "258840000001", Dest: "*144#",
+				DataCoding: dc,
+				Message:    msg,
+				TLVs:       map[uint16][]byte{TagUSSDServiceOp: {OpUSSRRequest}, TagITSSessionInfo: {7, 0}},
+			}
+			body := in.Bytes()
+			// after dest_addr: esm_class, protocol_id, priority_flag, two empty
+			// time strings, registered_delivery, replace_if_present,
+			// data_coding, sm_default_msg_id
+			smLen := body[bytes.Index(body, []byte("*144#\x00"))+len("*144#\x00")+9]
+			if tt.payload && smLen != 0 {
+				t.Fatalf("sm_length = %d, want 0 with message_payload", smLen)
+			}
+			if !tt.payload && int(smLen) != len(msg) {
+				t.Fatalf("sm_length = %d, want %d", smLen, len(msg))
+			}
+
+			out, err := ParseShortMessage(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := out.Text(); got != tt.text {
+				t.Fatalf("Text() = %q, want %q", got, tt.text)
+			}
+			if _, ok := out.TLVs[TagMessagePayload]; ok {
+				t.Fatal("message_payload left in TLVs")
+			}
+			if op, _ := out.ServiceOp(); op != OpUSSRRequest {
+				t.Fatalf("ServiceOp() = %d, want %d", op, OpUSSRRequest)
+			}
+			if its := out.TLVs[TagITSSessionInfo]; !bytes.Equal(its, []byte{7, 0}) {
+				t.Fatalf("its_session_info = %v", its)
+			}
+		})
+	}
+}
+
+func TestTextFromPayloadTLV(t *testing.T) {
+	m := ShortMessage{TLVs: map[uint16][]byte{TagMessagePayload: []byte("hello")}}
+	if got := m.Text(); got != "hello" {
+		t.Fatalf("Text() = %q", got)
+	}
+}
+
+func TestParseShortMessageTruncated(t *testing.T) {
+	body := ShortMessage{Source: "1", Dest: "2", Message: []byte("hello")}.Bytes()
+	if _, err := ParseShortMessage(body[:len(body)-2]); err == nil {
+		t.Fatal("want error for truncated body")
+	}
+}
+
+func TestPDURoundTrip(t *testing.T) {
+	in := &PDU{ID: SubmitSM, Status: StatusOK, Seq: 42, Body: []byte("body")}
+	out, err := ReadPDU(bytes.NewReader(in.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.ID != in.ID || out.Seq != in.Seq || !bytes.Equal(out.Body, in.Body) {
+		t.Fatalf("got %+v, want %+v", out, in)
+	}
+}
+
+func TestClientAgainstSMSC(t *testing.T) {
+	long := "Escolha\n" + strings.Repeat("Opção ", 30) // UCS-2, over 254 bytes
+	r := router.New("/home")
+	r.SHOW("/home", func(c *router.Ctx) core.Reply { return core.CON(long) })
+	r.INPUT("/home", func(c *router.Ctx) core.Reply { return core.END("Obrigado " + c.Req.Text) })
+	eng := core.New(r.Mount(), core.Config{Store: store.NewInMemoryStore(time.Minute)})
+
+	smsc, err := NewSMSC("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer smsc.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go New(eng, Config{Addr: smsc.Addr(), SystemID: "test"}).Run(ctx)
+	if !smsc.WaitBound(5 * time.Second) {
+		t.Fatal("esme did not bind")
+	}
+
+	next := func() ShortMessage {
+		t.Helper()
+		select {
+		case sm := <-smsc.Submitted():
+			return sm
+		case <-time.After(5 * time.Second):
+			t.Fatal("no submit_sm")
+			return ShortMessage{}
+		}
+	}
+
+	if err := smsc.Dial("258840000001", "*144#", 3); err != nil {
+		t.Fatal(err)
+	}
+	sm := next()
+	if op, _ := sm.ServiceOp(); op != OpUSSRRequest {
+		t.Fatalf("op = %d, want USSR request", op)
+	}
+	if sm.Text() != long {
+		t.Fatalf("screen = %q, want %q", sm.Text(), long)
+	}
+	if sm.Dest != "258840000001" || sm.Source != "*144#" {
+		t.Fatalf("addresses = %q -> %q", sm.Source, sm.Dest)
+	}
+
+	if err := smsc.Continue("258840000001", "*144#", "1", 3); err != nil {
+		t.Fatal(err)
+	}
+	sm = next()
+	if op, _ := sm.ServiceOp(); op != OpPSSRResponse {
+		t.Fatalf("op = %d, want PSSR response", op)
+	}
+	if sm.Text() != "Obrigado 1" {
+		t.Fatalf("screen = %q", sm.Text())
+	}
+	if its := sm.TLVs[TagITSSessionInfo]; len(its) != 2 || its[0] != 3 || its[1]&itsEndOfSession == 0 {
+		t.Fatalf("its_session_info = %v, want session 3 with end flag", its)
+	}
+}
@@ -0,0 +1,161 @@
+package smpp
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// SMSC is a minimal local SMSC stand-in for tests and demos. It accepts
+// transceiver binds, answers enquire_link, records submit_sm PDUs and lets the
+// caller inject MO USSD messages with Dial/Continue.
+//
+//	smsc, _ := smpp.NewSMSC("127.0.0.1:0")
+//	defer smsc.Close()
+//	go smpp.New(eng, smpp.Config{Addr: smsc.Addr()}).Run(ctx)
+//	_ = smsc.Dial("258840000001", "*144#", 1)
+//	mt := <-smsc.Submitted() // first screen
+type SMSC struct {
+	ln  net.Listener
+	out chan ShortMessage
+
+	// Auth, if set, decides whether a bind is accepted.
+	Auth func(Bind) bool
+
+	mu    sync.Mutex
+	conns []net.Conn
+	seq   uint32
+	wmu   sync.Mutex
+	bound chan struct{}
+	once  sync.Once
+}
+
+// NewSMSC listens on addr (use "127.0.0.1:0" for a random port).
+func NewSMSC(addr string) (*SMSC, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &SMSC{ln: ln, out: make(chan ShortMessage, 64), bound: make(chan struct{})}
+	go s.accept()
+	return s, nil
+}
+
+func (s *SMSC) Addr() string { return s.ln.Addr().String() }
+
+// Submitted delivers every submit_sm received from bound ESMEs.
+func (s *SMSC) Submitted() <-chan ShortMessage { return s.out }
+
+// WaitBound blocks until an ESME has bound or d elapses.
+func (s *SMSC) WaitBound(d time.Duration) bool {
+	select {
+	case <-s.bound:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+
+// Dial simulates a subscriber dialling code (PSSR indication, session begin).
+func (s *SMSC) Dial(msisdn, code string, session byte) error {
+	return s.Deliver(mo(msisdn, code, code, OpPSSRIndication, session))
+}
+
+// Continue simulates a subscriber answering the current screen (USSR confirm).
+func (s *SMSC) Continue(msisdn, code, input string, session byte) error {
+	return s.Deliver(mo(msisdn, code, input, OpUSSRConfirm, session))
+}
+
+// Deliver sends an arbitrary deliver_sm to the most recently bound ESME.
+func (s *SMSC) Deliver(sm ShortMessage) error {
+	s.mu.Lock()
+	if len(s.conns) == 0 {
+		s.mu.Unlock()
+		return errors.New("smpp: no bound esme")
+	}
+	conn := s.conns[len(s.conns)-1]
+	s.seq++
+	seq := s.seq
+	s.mu.Unlock()
+	return s.write(conn, &PDU{ID: DeliverSM, Seq: seq, Body: sm.Bytes()})
+}
+
+// Kick drops every ESME connection (to exercise reconnection).
+func (s *SMSC) Kick() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conns {
+		_ = c.Close()
+	}
+	s.conns = nil
+}
+
+func (s *SMSC) Close() error {
+	s.Kick()
+	return s.ln.Close()
+}
+
+func (s *SMSC) accept() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.serve(conn)
+	}
+}
+
+func (s *SMSC) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		p, err := ReadPDU(conn)
+		if err != nil {
+			return
+		}
+		switch p.ID {
+		case BindTransceiver:
+			b, err := ParseBind(p.Body)
+			if err != nil || (s.Auth != nil && !s.Auth(b)) {
+				_ = s.write(conn, &PDU{ID: BindTransceiverResp, Status: StatusBindFail, Seq: p.Seq, Body: []byte{0}})
+				return
+			}
+			_ = s.write(conn, &PDU{ID: BindTransceiverResp, Seq: p.Seq, Body: []byte("smsc\x00")})
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			s.once.Do(func() { close(s.bound) })
+		case EnquireLink:
+			_ = s.write(conn, &PDU{ID: EnquireLinkResp, Seq: p.Seq})
+		case SubmitSM:
+			_ = s.write(conn, &PDU{ID: SubmitSMResp, Seq: p.Seq, Body: []byte{0}})
+			if sm, err := ParseShortMessage(p.Body); err == nil {
+				s.out <- sm
+			}
+		case Unbind:
+			_ = s.write(conn, &PDU{ID: UnbindResp, Seq: p.Seq})
+			return
+		}
+	}
+}
+
+func (s *SMSC) write(conn net.Conn, p *PDU) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	_, err := conn.Write(p.Bytes())
+	return err
+}
+
+func mo(msisdn, code, text string, op, session byte) ShortMessage {
+	dc, msg := encodeText(text)
+	return ShortMessage{
+		SourceTON: 1, SourceNPI: 1, Source: msisdn,
+		Dest:       code,
+		DataCoding: dc,
+		Message:    msg,
+		TLVs: map[uint16][]byte{
+			TagUSSDServiceOp:  {op},
+			TagITSSessionInfo: {session, 0},
+		},
+	}
+}
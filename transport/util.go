@@ -3,6 +3,9 @@ package transport
 import (
 	"net"
 	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
 )
 
 func asString(v any) string {
@@ -15,12 +18,159 @@ func asString(v any) string {
 	return ""
 }
 
-// best-effort client IP (for logs/Meta)
-func clientIP(r *http.Request) string {
-	// if behind proxies, extend this (X-Forwarded-For, etc.)
+// TrustedProxies is a set of load balancers/proxies (IPs or CIDRs) whose
+// forwarding headers are honoured. A nil *TrustedProxies trusts none.
+type TrustedProxies struct {
+	ps []netip.Prefix
+}
+
+// ParseTrustedProxies parses IPs and CIDRs for Policy.Proxies.
+func ParseTrustedProxies(cidrs ...string) (*TrustedProxies, error) {
+	ps, err := parsePrefixes(cidrs)
+	if err != nil {
+		return nil, err
+	}
+	return &TrustedProxies{ps: ps}, nil
+}
+
+var trustedProxies atomic.Pointer[TrustedProxies]
+
+// SetTrustedProxies declares the default load balancers/proxies in front of
+// the USSD endpoints (IPs or CIDRs), for handlers without Policy.Proxies.
+// Forwarding headers are only honoured when the connection comes from one of
+// them. Call with no arguments to trust none (default).
+func SetTrustedProxies(cidrs ...string) error {
+	tp, err := ParseTrustedProxies(cidrs...)
+	if err != nil {
+		return err
+	}
+	trustedProxies.Store(tp)
+	return nil
+}
+
+// ClientIP returns the best-effort client IP (for logs/Meta and allow-lists)
+// using the default proxies (SetTrustedProxies).
+func ClientIP(r *http.Request) string { return clientIP(r) }
+
+func clientIP(r *http.Request) string { return trustedProxies.Load().ClientIP(r) }
+
+// ClientIP returns the best-effort client IP. Behind one of tp's proxies it
+// walks X-Forwarded-For from the right, then Forwarded, then X-Real-IP,
+// returning the first untrusted address.
+func (tp *TrustedProxies) ClientIP(r *http.Request) string {
 	host, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
-		return r.RemoteAddr
+		host = r.RemoteAddr
+	}
+	if !tp.trusted(host) {
+		return host
+	}
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := strings.TrimSpace(hops[i])
+			if ip != "" && !tp.trusted(ip) {
+				return ip
+			}
+		}
+	}
+	if fwd := r.Header.Values("Forwarded"); len(fwd) > 0 {
+		var fors []string
+		for _, elem := range strings.Split(strings.Join(fwd, ","), ",") {
+			for _, pair := range strings.Split(elem, ";") {
+				k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(k, "for") {
+					fors = append(fors, forwardedHost(v))
+				}
+			}
+		}
+		for i := len(fors) - 1; i >= 0; i-- {
+			if fors[i] != "" && !tp.trusted(fors[i]) {
+				return fors[i]
+			}
+		}
+	}
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+		return ip
 	}
 	return host
 }
+
+// forwardedHost strips quotes, brackets and ports from a Forwarded "for" value.
+func forwardedHost(v string) string {
+	v = strings.Trim(v, `"`)
+	if strings.HasPrefix(v, "[") {
+		if i := strings.IndexByte(v, ']'); i > 0 {
+			return v[1:i]
+		}
+	}
+	if h, _, err := net.SplitHostPort(v); err == nil {
+		return h
+	}
+	return v
+}
+
+func (tp *TrustedProxies) trusted(ip string) bool {
+	if tp == nil {
+		return false
+	}
+	return inPrefixes(tp.ps, ip)
+}
+
+// AllowIPs wraps a vendor handler so only the aggregator's published ranges
+// (IPs or CIDRs) can reach it; other clients get 403. The client IP honours
+// proxies if given, otherwise SetTrustedProxies.
+//
+//	at, err := transport.AllowIPs([]string{"196.201.214.0/24"}, transport.AfricaTalkingHandler(eng))
+func AllowIPs(cidrs []string, h http.Handler, proxies ...*TrustedProxies) (http.Handler, error) {
+	ps, err := parsePrefixes(cidrs)
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if len(proxies) > 0 {
+		p.Proxies = proxies[0]
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !inPrefixes(ps, p.ClientIP(r)) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	}), nil
+}
+
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	ps := make([]netip.Prefix, 0, len(cidrs))
+	for _, c := range cidrs {
+		c = strings.TrimSpace(c)
+		if !strings.Contains(c, "/") {
+			a, err := netip.ParseAddr(c)
+			if err != nil {
+				return nil, err
+			}
+			ps = append(ps, netip.PrefixFrom(a, a.BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(c)
+		if err != nil {
+			return nil, err
+		}
+		ps = append(ps, p.Masked())
+	}
+	return ps, nil
+}
+
+func inPrefixes(ps []netip.Prefix, ip string) bool {
+	a, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	a = a.Unmap()
+	for _, p := range ps {
+		if p.Contains(a) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestTrustedProxiesClientIP(t *testing.T) {
+	tp, err := ParseTrustedProxies("10.0.0.0/8", "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{"direct", "203.0.113.5:1234", nil, "203.0.113.5"},
+		{"untrusted peer ignores headers", "203.0.113.5:1234", map[string]string{"X-Forwarded-For": "198.51.100.9"}, "203.0.113.5"},
+		{"xff", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "198.51.100.9"}, "198.51.100.9"},
+		{"xff skips trusted hops", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.9, 10.9.9.9"}, "198.51.100.9"},
+		{"forwarded", "192.0.2.1:80", map[string]string{"Forwarded": `for="[2001:db8::1]:4711";proto=https`}, "2001:db8::1"},
+		{"x-real-ip", "10.1.2.3:1234", map[string]string{"X-Real-IP": "198.51.100.7"}, "198.51.100.7"},
+		{"only proxies", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "10.4.4.4"}, "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			r.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if got := tp.ClientIP(r); got != tt.want {
+				t.Fatalf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.RemoteAddr = "10.1.2.3:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.9")
+	if got := (*TrustedProxies)(nil).ClientIP(r); got != "10.1.2.3" {
+		t.Fatalf("nil proxies: ClientIP = %q, want the peer", got)
+	}
+}
+
+func TestParseTrustedProxiesInvalid(t *testing.T) {
+	if _, err := ParseTrustedProxies("not-an-ip"); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func TestHandlerProxies(t *testing.T) {
+	lb, _ := ParseTrustedProxies("10.0.0.0/8")
+	post := func(h http.Handler) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(url.Values{"sessionId": {"s"}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.RemoteAddr = "10.1.2.3:1234"
+		r.Header.Set("X-Forwarded-For", "196.201.214.10")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	var c capture
+	post(AfricaTalkingHandler(&c, ATPolicy(Policy{Proxies: lb})))
+	if c.req.Meta["ip"] != "196.201.214.10" {
+		t.Fatalf("Meta[ip] = %q behind a trusted proxy", c.req.Meta["ip"])
+	}
+	post(AfricaTalkingHandler(&c))
+	if c.req.Meta["ip"] != "10.1.2.3" {
+		t.Fatalf("Meta[ip] = %q without proxies, want the peer", c.req.Meta["ip"])
+	}
+
+	allowed, err := AllowIPs([]string{"196.201.214.0/24"}, AfricaTalkingHandler(&c), lb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := post(allowed); w.Code != http.StatusOK {
+		t.Fatalf("AllowIPs behind proxy: status %d", w.Code)
+	}
+	denied, _ := AllowIPs([]string{"196.201.214.0/24"}, AfricaTalkingHandler(&c))
+	if w := post(denied); w.Code != http.StatusForbidden {
+		t.Fatalf("AllowIPs without proxies: status %d, want 403", w.Code)
+	}
+}
@@ -9,16 +9,23 @@ import (
 )
 
 // Example JSON shape (customize via options if your instance differs):
-// IN:  {"sessionId":"VDC-123", "msisdn":"+25884...", "userInput":"1"}
+// IN:  {"sessionId":"VDC-123", "msisdn":"+25884...", "serviceCode":"*144#", "userInput":"1"}
 // OUT: {"type":"Response", "text":"CON <message>"}
-func VodacomHandler(eng *core.Engine, opts ...VodaOption) http.Handler {
+//
+// An inbound "type" of "Release" or "Timeout" (see VodaKinds) is forwarded as
+// core.KindAbort / core.KindTimeout.
+func VodacomHandler(eng core.Handler, opts ...VodaOption) http.Handler {
 	cfg := vodaConfig{
-		FieldSessionID: "sessionId",
-		FieldMsisdn:    "msisdn",
-		FieldText:      "userInput",
-		RespTypeKey:    "type",
-		RespTextKey:    "text",
-		RespTypeValue:  "Response",
+		FieldSessionID:   "sessionId",
+		FieldMsisdn:      "msisdn",
+		FieldServiceCode: "serviceCode",
+		FieldText:        "userInput",
+		RespTypeKey:      "type",
+		RespTextKey:      "text",
+		RespTypeValue:    "Response",
+		FieldType:        "type",
+		TypeAbort:        "Release",
+		TypeTimeout:      "Timeout",
 	}
 	for _, o := range opts {
 		o(&cfg)
@@ -30,14 +37,25 @@ func VodacomHandler(eng *core.Engine, opts ...VodaOption) http.Handler {
 			return
 		}
 		req := core.Request{
-			SessionID: asString(in[cfg.FieldSessionID]),
-			Msisdn:    strings.TrimSpace(asString(in[cfg.FieldMsisdn])),
-			Text:      strings.TrimSpace(asString(in[cfg.FieldText])),
+			SessionID:   asString(in[cfg.FieldSessionID]),
+			Msisdn:      strings.TrimSpace(asString(in[cfg.FieldMsisdn])),
+			ServiceCode: strings.TrimSpace(asString(in[cfg.FieldServiceCode])),
+			Text:        strings.TrimSpace(asString(in[cfg.FieldText])),
 			Meta: map[string]string{
 				"vendor": "vodacom",
-				"ip":     clientIP(r),
+				"ip":     cfg.Policy.ClientIP(r),
 			},
 		}
+		switch asString(in[cfg.FieldType]) {
+		case cfg.TypeAbort:
+			req.Kind = core.KindAbort
+		case cfg.TypeTimeout:
+			req.Kind = core.KindTimeout
+		}
+		if err := cfg.Policy.Normalize(&req); err != nil {
+			http.Error(w, "bad msisdn", http.StatusBadRequest)
+			return
+		}
 		rep, _ := eng.Handle(r.Context(), req)
 
 		out := map[string]any{
@@ -54,18 +72,33 @@ func VodacomHandler(eng *core.Engine, opts ...VodaOption) http.Handler {
 }
 
 type vodaConfig struct {
-	FieldSessionID string
-	FieldMsisdn    string
-	FieldText      string
+	FieldSessionID   string
+	FieldMsisdn      string
+	FieldServiceCode string
+	FieldText        string
 
 	RespTypeKey   string
 	RespTextKey   string
 	RespTypeValue string
+
+	FieldType   string // inbound request type
+	TypeAbort   string
+	TypeTimeout string
+
+	Policy Policy
 }
 type VodaOption func(*vodaConfig)
 
-func VodaFields(sessionID, msisdn, text string) VodaOption {
+// VodaPolicy sets this handler's request policy (default: the package defaults).
+func VodaPolicy(p Policy) VodaOption { return func(c *vodaConfig) { c.Policy = p } }
+
+// VodaFields overrides the inbound JSON keys. Empty values keep the default;
+// an optional fourth key overrides serviceCode.
+func VodaFields(sessionID, msisdn, text string, serviceCode ...string) VodaOption {
 	return func(c *vodaConfig) {
+		if len(serviceCode) > 0 && serviceCode[0] != "" {
+			c.FieldServiceCode = serviceCode[0]
+		}
 		if sessionID != "" {
 			c.FieldSessionID = sessionID
 		}
@@ -90,3 +123,18 @@ func VodaRespKeys(typeKey, textKey, typeVal string) VodaOption {
 		}
 	}
 }
+
+// VodaKinds overrides the inbound type field and the values that signal abort/timeout.
+func VodaKinds(typeKey, abortVal, timeoutVal string) VodaOption {
+	return func(c *vodaConfig) {
+		if typeKey != "" {
+			c.FieldType = typeKey
+		}
+		if abortVal != "" {
+			c.TypeAbort = abortVal
+		}
+		if timeoutVal != "" {
+			c.TypeTimeout = timeoutVal
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/grahms/cardinal/core"
+)
+
+// XMLHandler adapts XML payloads (plain or XML-RPC style) by configuring element
+// paths for the inbound fields and a text/template for the reply.
+//
+// Paths are "/"-separated element names from the document root. A step of the
+// form member[name=X] selects the element whose <name> child reads X, which
+// covers XML-RPC structs. The value is the trimmed text of the selected element
+// (including descendants, so <value><string>..</string></value> works too).
+//
+// InKind selects an optional element carrying the request type; Kinds maps its
+// raw values to core kinds (unmapped values leave Kind unspecified). The raw
+// value is kept in Meta["kind"].
+//
+// Template data: .SessionID .Msisdn .ServiceCode .Message .Flag .Continue
+// (strings are XML-escaped; .Flag is OutContinue or OutEnd).
+//
+// Example:
+//
+//	mux.Handle("/ussd/xml", transport.XMLHandler(eng,
+//	    XMLMap{InSessionID: "ussd/session", InMsisdn: "ussd/msisdn", InText: "ussd/input"},
+//	    XMLMap{OutTemplate: `<ussd><type>{{.Flag}}</type><msg>{{.Message}}</msg></ussd>`},
+//	))
+//
+// For XML-RPC gateways (Huawei/Comviva style) use XMLRPCIn and XMLRPCOut.
+func XMLHandler(eng core.Handler, in XMLMap, out XMLMap) http.Handler {
+	if out.OutContinue == "" {
+		out.OutContinue = "CON"
+	}
+	if out.OutEnd == "" {
+		out.OutEnd = "END"
+	}
+	if out.OutContentType == "" {
+		out.OutContentType = "text/xml; charset=utf-8"
+	}
+	if out.OutTemplate == "" {
+		out.OutTemplate = `<?xml version="1.0" encoding="UTF-8"?><response><type>{{.Flag}}</type><message>{{.Message}}</message></response>`
+	}
+	tmpl := template.Must(template.New("xml").Parse(out.OutTemplate))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		doc, err := parseXML(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "bad xml", http.StatusBadRequest)
+			return
+		}
+		req := core.Request{
+			SessionID:   doc.find(in.InSessionID),
+			Msisdn:      strings.TrimSpace(doc.find(in.InMsisdn)),
+			ServiceCode: doc.find(in.InServiceCode),
+			Text:        strings.TrimSpace(doc.find(in.InText)),
+			Meta: map[string]string{
+				"vendor": "xml",
+				"ip":     in.Policy.ClientIP(r),
+			},
+		}
+		if in.InKind != "" {
+			raw := doc.find(in.InKind)
+			req.Meta["kind"] = raw
+			req.Kind = in.Kinds[raw]
+		}
+		if err := in.Policy.Normalize(&req); err != nil {
+			http.Error(w, "bad msisdn", http.StatusBadRequest)
+			return
+		}
+		rep, _ := eng.Handle(r.Context(), req)
+
+		flag := out.OutEnd
+		if rep.Continue {
+			flag = out.OutContinue
+		}
+		var buf bytes.Buffer
+		err = tmpl.Execute(&buf, map[string]any{
+			"SessionID":   xmlEscape(req.SessionID),
+			"Msisdn":      xmlEscape(req.Msisdn),
+			"ServiceCode": xmlEscape(req.ServiceCode),
+			"Message":     xmlEscape(rep.Message),
+			"Flag":        xmlEscape(flag),
+			"Continue":    rep.Continue,
+		})
+		if err != nil {
+			http.Error(w, "template error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", out.OutContentType)
+		_, _ = w.Write(buf.Bytes())
+	})
+}
+
+type XMLMap struct {
+	InSessionID   string
+	InMsisdn      string
+	InText        string
+	InServiceCode string
+	InKind        string               // optional element carrying the request type
+	Kinds         map[string]core.Kind // raw InKind value -> kind
+
+	OutTemplate    string // text/template for the reply body
+	OutContinue    string // .Flag when the session continues (default "CON")
+	OutEnd         string // .Flag when the session ends (default "END")
+	OutContentType string // default "text/xml; charset=utf-8"
+
+	Policy Policy // read from the inbound map
+}
+
+// XMLRPCMember returns the path of a top-level struct member in an XML-RPC methodCall.
+func XMLRPCMember(name string) string {
+	return "methodCall/params/param/value/struct/member[name=" + name + "]/value"
+}
+
+// XMLRPCIn maps the common Huawei/Comviva XML-RPC request members.
+var XMLRPCIn = XMLMap{
+	InSessionID:   XMLRPCMember("TransactionId"),
+	InMsisdn:      XMLRPCMember("MSISDN"),
+	InText:        XMLRPCMember("USSDRequestString"),
+	InServiceCode: XMLRPCMember("USSDServiceCode"),
+}
+
+// XMLRPCOut answers with a methodResponse carrying action "request" (continue) or "end".
+var XMLRPCOut = XMLMap{
+	OutContinue: "request",
+	OutEnd:      "end",
+	OutTemplate: `<?xml version="1.0" encoding="UTF-8"?>
+<methodResponse><params><param><value><struct>
+<member><name>TransactionId</name><value><string>{{.SessionID}}</string></value></member>
+<member><name>USSDResponseString</name><value><string>{{.Message}}</string></value></member>
+<member><name>action</name><value><string>{{.Flag}}</string></value></member>
+</struct></value></param></params></methodResponse>`,
+}
+
+/* ---------- minimal XML tree ---------- */
+
+type xmlNode struct {
+	name     string
+	text     strings.Builder
+	children []*xmlNode
+}
+
+func parseXML(r io.Reader) (*xmlNode, error) {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	root := &xmlNode{}
+	stack := []*xmlNode{root}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		top := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &xmlNode{name: t.Name.Local}
+			top.children = append(top.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			for _, n := range stack[1:] {
+				n.text.Write(t)
+			}
+		}
+	}
+	if len(root.children) == 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return root, nil
+}
+
+// find resolves path from the document root; missing paths yield "".
+func (n *xmlNode) find(path string) string {
+	if path == "" {
+		return ""
+	}
+	cur := n
+	for _, step := range strings.Split(strings.Trim(path, "/"), "/") {
+		name, key, val := step, "", ""
+		if i := strings.IndexByte(step, '['); i > 0 && strings.HasSuffix(step, "]") {
+			name = step[:i]
+			key, val, _ = strings.Cut(step[i+1:len(step)-1], "=")
+		}
+		var next *xmlNode
+		for _, c := range cur.children {
+			if c.name != name {
+				continue
+			}
+			if key != "" && c.child(key) != val {
+				continue
+			}
+			next = c
+			break
+		}
+		if next == nil {
+			return ""
+		}
+		cur = next
+	}
+	return strings.TrimSpace(cur.text.String())
+}
+
+func (n *xmlNode) child(name string) string {
+	for _, c := range n.children {
+		if c.name == name {
+			return strings.TrimSpace(c.text.String())
+		}
+	}
+	return ""
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,64 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grahms/cardinal/core"
+)
+
+func TestXMLKinds(t *testing.T) {
+	in := XMLMap{
+		InSessionID: "ussd/session",
+		InMsisdn:    "ussd/msisdn",
+		InText:      "ussd/input",
+		InKind:      "ussd/type",
+		Kinds:       map[string]core.Kind{"1": core.KindBegin, "2": core.KindContinue, "3": core.KindAbort, "4": core.KindTimeout},
+	}
+	tests := []struct {
+		raw  string
+		want core.Kind
+	}{
+		{"1", core.KindBegin},
+		{"2", core.KindContinue},
+		{"3", core.KindAbort},
+		{"4", core.KindTimeout},
+		{"9", core.KindUnspecified},
+		{"", core.KindUnspecified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			c := capture{rep: core.END("Bye")}
+			body := `<ussd><session>s</session><msisdn>+258840000001</msisdn><type>` + tt.raw + `</type><input>1</input></ussd>`
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			w := httptest.NewRecorder()
+			XMLHandler(&c, in, XMLMap{}).ServeHTTP(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d", w.Code)
+			}
+			if c.req.Kind != tt.want {
+				t.Fatalf("Kind = %v, want %v", c.req.Kind, tt.want)
+			}
+			if c.req.Meta["kind"] != tt.raw {
+				t.Fatalf(`Meta["kind"] = %q, want %q`, c.req.Meta["kind"], tt.raw)
+			}
+		})
+	}
+}
+
+func TestXMLBodyLimit(t *testing.T) {
+	in := XMLMap{InSessionID: "ussd/session", InMsisdn: "ussd/msisdn", InText: "ussd/input"}
+	body := `<ussd><session>s</session><msisdn>+258840000001</msisdn><input>` + strings.Repeat("1", 2<<20) + `</input></ussd>`
+	c := capture{rep: core.END("Bye")}
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	XMLHandler(&c, in, XMLMap{}).ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if c.req.SessionID != "" {
+		t.Fatal("oversized body reached the engine")
+	}
+}